@@ -83,7 +83,7 @@ func (n *Neural) initDeep(inputs int, layout []int, std float64, mean float64) {
 
 // Train implement Classifier interface
 func (n *Neural) Train(people *core.People, split float64, iterations int, verbosity int) {
-	n.initDeep(512, []int{64, 16, len(people.GetList())}, 0.5, 0)
+	n.initDeep(embeddingDim(people), []int{64, 16, len(people.GetList())}, 0.5, 0)
 	//trainer := training.NewTrainer(training.NewSGD(0.01, 0.5, 1e-6, true), 1)
 	//trainer := training.NewTrainer(training.NewSGD(0.005, 0.5, 1e-6, true), 50)
 	//trainer := training.NewBatchTrainer(training.NewSGD(0.005, 0.1, 0, true), 50, 300, 16)
@@ -97,7 +97,7 @@ func (n *Neural) Train(people *core.People, split float64, iterations int, verbo
 
 // BatchTrain implement Classifier interface
 func (n *Neural) BatchTrain(people *core.People, split float64, iterations int, verbosity int, batch int) {
-	n.initDeep(512, []int{64, 16, len(people.GetList())}, 0.5, 0)
+	n.initDeep(embeddingDim(people), []int{64, 16, len(people.GetList())}, 0.5, 0)
 	//solver := training.NewSGD(0.01, 0.5, 1e-6, true)
 	solver := training.NewAdam(0.02, 0.9, 0.999, 1e-8)
 	trainer := training.NewBatchTrainer(solver, verbosity, batch, 4)
@@ -105,8 +105,11 @@ func (n *Neural) BatchTrain(people *core.People, split float64, iterations int,
 	trainer.Train(n.ml, data, heldout, iterations)
 }
 
-// Predict implement Classifier interface
+// Predict implement Classifier interface, returns nil if not trained
 func (n *Neural) Predict(embedding []float32) []float64 {
+	if n.ml == nil {
+		return nil
+	}
 	return n.ml.Predict(convInputs(embedding))
 }
 
@@ -136,6 +139,18 @@ func convInputs(embedding []float32) []float64 {
 	return ret
 }
 
+// embeddingDim returns the embedding dimension of people, default to 512
+func embeddingDim(people *core.People) int {
+	for _, person := range people.GetList() {
+		for _, embedding := range person.GetEmbeddings() {
+			if l := len(embedding.GetValue()); l > 0 {
+				return l
+			}
+		}
+	}
+	return 512
+}
+
 func onehot(classes int, val int) []float64 {
 	res := make([]float64, classes)
 	res[val] = 1
@@ -17,6 +17,7 @@ import (
 	"github.com/bububa/camera"
 	"github.com/bububa/facenet"
 	"github.com/bububa/facenet/cmd/camera/server"
+	"github.com/bububa/facenet/core"
 	"github.com/llgcode/draw2d"
 )
 
@@ -27,6 +28,7 @@ var (
 	modelPath string
 	dbPath    string
 	fontPath  string
+	shift     float64
 )
 
 func init() {
@@ -38,6 +40,7 @@ func init() {
 	flag.StringVar(&modelPath, "model", "", "set facenet model path")
 	flag.StringVar(&dbPath, "db", "", "set db path")
 	flag.StringVar(&fontPath, "font", "", "set font path")
+	flag.Float64Var(&shift, "shift", 0.1, "set face detector shift factor, a larger one is faster but may miss faces")
 }
 
 func setup() error {
@@ -53,6 +56,7 @@ func setup() error {
 		facenet.WithModel(modelPath),
 		facenet.WithDB(dbPath),
 		facenet.WithFontPath(fontPath),
+		facenet.WithDetectOptions(core.WithExtractorOptions(core.WithShiftFactor(shift))),
 	)
 	if err != nil {
 		return err
@@ -65,7 +69,8 @@ func setup() error {
 	}, 9); err != nil {
 		return err
 	}
-	return nil
+	log.Println("warming up model...")
+	return estimator.Warmup()
 
 }
 
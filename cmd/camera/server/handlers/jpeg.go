@@ -37,7 +37,7 @@ func (s *JPEG) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if s.e != nil {
-		if markers, err := s.e.DetectFaces(img, DetectMinSize); err == nil {
+		if markers, err := s.e.DetectFacesContext(r.Context(), img, DetectMinSize); err == nil {
 			img = s.e.DrawMarkers(markers, TextColor, SuccessColor, FailedColor, StrokeWidth, false)
 		}
 	}
@@ -43,7 +43,7 @@ func (s *Socket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		if s.e != nil {
-			if markers, err := s.e.DetectFaces(img, DetectMinSize); err == nil {
+			if markers, err := s.e.DetectFacesContext(ctx, img, DetectMinSize); err == nil {
 				img = s.e.DrawMarkers(markers, TextColor, SuccessColor, FailedColor, StrokeWidth, false)
 			}
 		}
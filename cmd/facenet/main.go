@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"image"
 	"image/jpeg"
 	"io/fs"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/bububa/facenet"
 	"github.com/bububa/facenet/core"
+	"github.com/bububa/facenet/imageutil"
 )
 
 // Request request options
@@ -39,6 +41,13 @@ var (
 	updateAction string
 	deleteAction string
 	detectAction string
+	alignFaces   bool
+	metric       string
+	flipFaces    bool
+	mtcnnPath    string
+	minQuality   float64
+	landmarks    bool
+	tileSize     int
 )
 
 func init() {
@@ -51,6 +60,13 @@ func init() {
 	flag.StringVar(&updateAction, "update", "", "delete person, multiple names are separated by comma")
 	flag.StringVar(&detectAction, "detect", "", "detect faces in image file")
 	flag.BoolVar(&infoAction, "info", false, "people model info")
+	flag.BoolVar(&alignFaces, "align", false, "align faces by eyes before embedding")
+	flag.BoolVar(&landmarks, "landmarks", false, "search face landmarks to estimate head pose when detecting")
+	flag.IntVar(&tileSize, "tiles", 0, "detect faces in tiles of this size at native resolution, for small faces in high resolution images")
+	flag.BoolVar(&flipFaces, "flip", false, "average embeddings of faces and their mirror images")
+	flag.StringVar(&mtcnnPath, "mtcnn", "", "MTCNN frozen graph path, pigo face detector is used if empty")
+	flag.Float64Var(&minQuality, "min-quality", 0, "minimum face quality in [0, 1] for training")
+	flag.StringVar(&metric, "metric", "", "distance metric saved in db: euclidean, squared_euclidean or cosine")
 }
 
 func main() {
@@ -69,12 +85,34 @@ func main() {
 		log.Fatalln("[ERR] missing facenet model file path")
 	} else {
 		request.Model = cleanPath(wd, request.Model)
-		opts = append(opts, facenet.WithModel(request.Model))
+		opts = append(opts, facenet.WithModel(request.Model, core.WithFlip(flipFaces)))
 	}
 	if request.Font != "" {
 		request.Font = cleanPath(wd, request.Font)
 		opts = append(opts, facenet.WithFontPath(request.Font))
 	}
+	if alignFaces {
+		opts = append(opts, facenet.WithDetectOptions(core.WithAlignment(true)))
+	}
+	if landmarks {
+		opts = append(opts, facenet.WithDetectOptions(core.WithLandmarks(true)))
+	}
+	if tileSize > 0 {
+		opts = append(opts, facenet.WithDetectOptions(core.WithTiles(tileSize, 0)))
+	}
+	if minQuality > 0 {
+		opts = append(opts, facenet.WithDetectOptions(core.WithMinQuality(minQuality)))
+	}
+	if mtcnnPath != "" {
+		opts = append(opts, facenet.WithMTCNN(cleanPath(wd, mtcnnPath)))
+	}
+	if metric != "" {
+		value, found := core.Metric_value[strings.ToUpper(metric)]
+		if !found {
+			log.Fatalf("[ERR] unknown metric: %s\n", metric)
+		}
+		opts = append(opts, facenet.WithMetric(core.Metric(value)))
+	}
 	instance, err := facenet.New(opts...)
 	if err != nil {
 		log.Fatalln(err)
@@ -126,10 +164,15 @@ func main() {
 			log.Fatalln(err)
 		}
 		for _, marker := range markers.Markers() {
+			face := marker.Face()
+			var poseInfo string
+			if pose, ok := marker.Pose(); ok {
+				poseInfo = fmt.Sprintf(", yaw:%.1f, pitch:%.1f, roll:%.1f", pose.Yaw, pose.Pitch, pose.Roll)
+			}
 			if marker.Error() != nil {
-				log.Printf("label: %s, %v\n", marker.Label(), marker.Error())
+				log.Printf("label: %s, rect:%v%s, %v\n", marker.Label(), face.Rect(), poseInfo, marker.Error())
 			} else {
-				log.Printf("label: %s, distance:%f\n", marker.Label(), marker.Distance())
+				log.Printf("label: %s, distance:%f, rect:%v%s\n", marker.Label(), marker.Distance(), face.Rect(), poseInfo)
 			}
 		}
 		if request.Output != "" {
@@ -250,7 +293,12 @@ func extractPerson(ins *facenet.Estimator, filename string, person *core.Person,
 			log.Fatalf("[ERR] save image failed, %v\n", err)
 		}
 	}
-	log.Printf("[SUCCESS] label:%s, file:%s, face detected\n", label, baseName)
+	face := marker.Face()
+	if face.Quality == nil {
+		log.Printf("[SUCCESS] label:%s, file:%s, face detected\n", label, baseName)
+		return nil
+	}
+	log.Printf("[SUCCESS] label:%s, file:%s, face detected, quality:%.2f\n", label, baseName, face.Quality.Overall)
 	return nil
 }
 
@@ -260,11 +308,7 @@ func loadImage(filePath string) (image.Image, error) {
 		return nil, err
 	}
 	defer fn.Close()
-	img, _, err := image.Decode(fn)
-	if err != nil {
-		return nil, err
-	}
-	return img, nil
+	return imageutil.Load(fn)
 }
 
 func saveImage(img image.Image, filePath string) error {
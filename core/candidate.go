@@ -0,0 +1,24 @@
+package core
+
+// Candidate represents a person matched with a face embedding
+type Candidate struct {
+	// Person matched person
+	Person *Person
+	// Distance distance between the face and the nearest embedding of person in metric units
+	Distance float64
+	// Err reason the face does not match person, e.g. TooFarMatchErr or CollisionMatchErr, nil if matched
+	Err error
+	// Score classifier score of person, 0 without classifier
+	Score float64
+}
+
+// Matched returns true if the face matches person
+func (c Candidate) Matched() bool {
+	return c.Err == nil
+}
+
+// Collision returns true if the face is within the match distance of person but beyond the radius of collisions with other persons
+func (c Candidate) Collision() bool {
+	e, ok := c.Err.(Error)
+	return ok && e.Code == CollisionMatchErr
+}
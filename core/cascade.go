@@ -0,0 +1,107 @@
+package core
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+//go:embed cascade
+var cascadeFS embed.FS
+
+const (
+	// FacefinderCascade represents the face classification cascade filename
+	FacefinderCascade = "facefinder"
+	// PuplocCascade represents the pupil localization cascade filename
+	PuplocCascade = "puploc"
+	// LandmarkCascadeDir represents the facial landmark points cascades directory name
+	LandmarkCascadeDir = "lps"
+)
+
+// Cascades represents the pigo cascades used by an Extractor
+type Cascades struct {
+	facefinder *pigo.Pigo
+	puploc     *pigo.PuplocCascade
+	landmarks  map[string][]*FlpCascade
+}
+
+var (
+	defaultCascades     *Cascades
+	defaultCascadesErr  error
+	defaultCascadesOnce sync.Once
+)
+
+// DefaultCascades returns the embedded cascades, they are unpacked on first use
+func DefaultCascades() (*Cascades, error) {
+	defaultCascadesOnce.Do(func() {
+		fsys, err := fs.Sub(cascadeFS, "cascade")
+		if err != nil {
+			defaultCascadesErr = err
+			return
+		}
+		defaultCascades, defaultCascadesErr = LoadCascades(fsys)
+	})
+	return defaultCascades, defaultCascadesErr
+}
+
+// LoadCascadeDir loads cascades from a directory laid out as LoadCascades expects
+func LoadCascadeDir(dir string) (*Cascades, error) {
+	return LoadCascades(os.DirFS(dir))
+}
+
+// LoadCascades loads the FacefinderCascade file, the optional PuplocCascade file and the optional LandmarkCascadeDir directory from fsys.
+// Eyes are not found without PuplocCascade, and other landmarks are not found without LandmarkCascadeDir.
+func LoadCascades(fsys fs.FS) (*Cascades, error) {
+	buf, err := fs.ReadFile(fsys, FacefinderCascade)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Cascades)
+	if c.facefinder, err = unpackFacefinder(buf); err != nil {
+		return nil, err
+	}
+
+	buf, err = fs.ReadFile(fsys, PuplocCascade)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, err
+	}
+	pl := pigo.NewPuplocCascade()
+	if c.puploc, err = unpackPuploc(pl, PuplocCascade, buf); err != nil {
+		return nil, err
+	}
+
+	if _, err := fs.Stat(fsys, LandmarkCascadeDir); errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if c.landmarks, err = ReadCascadeFS(pl, fsys, LandmarkCascadeDir); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// unpackFacefinder unpacks the face classification cascade, a malformed cascade returns an error instead of panicking
+func unpackFacefinder(buf []byte) (classifier *pigo.Pigo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewError(InvalidCascadeErr, fmt.Sprintf("malformed %s cascade: %v", FacefinderCascade, r))
+		}
+	}()
+	return pigo.NewPigo().Unpack(buf)
+}
+
+// unpackPuploc unpacks a pupil or landmark point localization cascade, a malformed cascade returns an error instead of panicking
+func unpackPuploc(plc *pigo.PuplocCascade, name string, buf []byte) (cascade *pigo.PuplocCascade, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewError(InvalidCascadeErr, fmt.Sprintf("malformed %s cascade: %v", name, r))
+		}
+	}()
+	return plc.UnpackCascade(buf)
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCascadeDir(t *testing.T) {
+	cascades, err := LoadCascadeDir("cascade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotNil(t, cascades.facefinder)
+	assert.NotNil(t, cascades.puploc)
+	assert.Len(t, cascades.landmarks, 9)
+}
+
+func TestLoadCascades(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		_, err := LoadCascades(fstest.MapFS{})
+		assert.Error(t, err)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := LoadCascades(fstest.MapFS{
+			FacefinderCascade: &fstest.MapFile{Data: []byte("not a cascade")},
+		})
+		if assert.Error(t, err) {
+			assert.Equal(t, InvalidCascadeErr, err.(Error).Code)
+		}
+	})
+}
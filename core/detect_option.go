@@ -0,0 +1,184 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/bububa/facenet/imageutil"
+)
+
+// DetectOption represents face detection pipeline option interface
+type DetectOption interface {
+	apply(*detectOptions)
+}
+
+type detectOptionFunc func(*detectOptions)
+
+func (fn detectOptionFunc) apply(opts *detectOptions) {
+	fn(opts)
+}
+
+// detectOptions represents face detection pipeline settings
+type detectOptions struct {
+	align       bool
+	landmarks   bool
+	singlePass  bool
+	extractor   []ExtractorOption
+	detector    Detector
+	quality     bool
+	minQuality  float64
+	tileSize    int
+	tileOverlap int
+}
+
+func newDetectOptions(opts ...DetectOption) *detectOptions {
+	ret := new(detectOptions)
+	for _, opt := range opts {
+		opt.apply(ret)
+	}
+	return ret
+}
+
+// findLandmarks returns true if the pipeline needs face landmarks
+func (o *detectOptions) findLandmarks() bool {
+	return o.landmarks || o.align || o.scoreQuality()
+}
+
+// scoreQuality returns true if the pipeline needs face quality
+func (o *detectOptions) scoreQuality() bool {
+	return o.quality || o.minQuality > 0
+}
+
+// checkQuality returns LowQualityErr if face is below the minimum quality
+func (o *detectOptions) checkQuality(face Face) error {
+	if o.minQuality <= 0 {
+		return nil
+	}
+	var overall float64
+	if face.Quality != nil {
+		overall = face.Quality.Overall
+	}
+	if overall < o.minQuality {
+		return NewError(LowQualityErr, fmt.Sprintf("face quality %.2f is below %.2f", overall, o.minQuality))
+	}
+	return nil
+}
+
+// source returns the image to detect faces in and crop them from, downscaled to MaxImageSize unless tiled
+func (o *detectOptions) source(img image.Image) image.Image {
+	if o.tileSize > 0 {
+		return img
+	}
+	return imageutil.NormalizeImage(img, MaxImageSize)
+}
+
+// customDetector returns the custom detector wrapped by a TiledDetector if tiled, nil for the default pigo detector
+func (o *detectOptions) customDetector(minSize int) Detector {
+	if o.tileSize > 0 {
+		detector := o.detector
+		if detector == nil {
+			detector = NewExtractor(minSize, o.extractor...)
+		}
+		return NewTiledDetector(detector, o.tileSize, o.tileOverlap)
+	}
+	return o.detector
+}
+
+// extract detects faces in one pass with the selected detector
+func (o *detectOptions) extract(ctx context.Context, img image.Image, minSize int) (Faces, error) {
+	if detector := o.customDetector(minSize); detector != nil {
+		return detector.Detect(ctx, img, o.findLandmarks(), minSize)
+	}
+	return ExtractContext(ctx, img, o.findLandmarks(), minSize, o.extractor...)
+}
+
+// extractMultiple detects multiple faces with the selected detector and detection mode
+func (o *detectOptions) extractMultiple(ctx context.Context, img image.Image, minSize int) (Faces, error) {
+	if detector := o.customDetector(minSize); detector != nil {
+		return detectMultiple(ctx, detector, img, o.findLandmarks(), minSize)
+	}
+	if o.singlePass {
+		return ExtractMultipleContext(ctx, img, o.findLandmarks(), minSize, o.extractor...)
+	}
+	return TrySizeExtractMultipleContext(ctx, img, o.findLandmarks(), minSize, o.extractor...)
+}
+
+// extractSingle detects single face with the selected detector and detection mode
+func (o *detectOptions) extractSingle(ctx context.Context, img image.Image, minSize int) (Face, error) {
+	if detector := o.customDetector(minSize); detector != nil {
+		return detectSingle(ctx, detector, img, o.findLandmarks(), minSize)
+	}
+	if o.singlePass {
+		return ExtractSingleContext(ctx, img, o.findLandmarks(), minSize, o.extractor...)
+	}
+	return TrySizeExtractSingleContext(ctx, img, o.findLandmarks(), minSize, o.extractor...)
+}
+
+// WithAlignment rotates and scales face crops so that eyes are horizontal at canonical positions before embedding
+func WithAlignment(align bool) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.align = align
+	})
+}
+
+// WithLandmarks searches eyes and face landmarks of detected faces, they are always searched for alignment and quality checks
+func WithLandmarks(landmarks bool) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.landmarks = landmarks
+	})
+}
+
+// WithExtractorOptions set face detector settings, e.g. a coarse shift factor to trade recall for speed
+func WithExtractorOptions(extractorOpts ...ExtractorOption) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.extractor = append(opts.extractor, extractorOpts...)
+	})
+}
+
+// WithSinglePass detects faces in one pyramid pass over all face sizes instead of retrying the detection with growing minSize
+func WithSinglePass(singlePass bool) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.singlePass = singlePass
+	})
+}
+
+// WithDetector replaces the default pigo face detector, e.g. with an MTCNN detector.
+// Custom detectors always detect in a single pass, extractor options only apply to the default detector.
+func WithDetector(detector Detector) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.detector = detector
+	})
+}
+
+// CloseDetector releases the resources of the detector set by WithDetector in opts, e.g. the MTCNN session
+func CloseDetector(opts ...DetectOption) error {
+	if closer, ok := newDetectOptions(opts...).detector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// WithQuality scores the quality of detected faces, landmarks are searched to score their completeness
+func WithQuality(quality bool) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.quality = quality
+	})
+}
+
+// WithMinQuality scores the quality of detected faces and rejects faces with Quality.Overall below minQuality when training
+func WithMinQuality(minQuality float64) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.minQuality = minQuality
+	})
+}
+
+// WithTiles detects faces in overlapping tiles of tileSize pixels at native resolution and crops them from the full resolution image,
+// for small faces in high resolution images. Tiles are detected in a single pass, tileSize 0 disables tiles and overlap defaults to a third of tileSize.
+func WithTiles(tileSize int, overlap int) DetectOption {
+	return detectOptionFunc(func(opts *detectOptions) {
+		opts.tileSize = tileSize
+		opts.tileOverlap = overlap
+	})
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"context"
+	"image"
+)
+
+// Detector represents a face detection backend
+type Detector interface {
+	// Detect returns the faces not smaller than minSize found in img
+	Detect(ctx context.Context, img image.Image, findLandmarks bool, minSize int) (Faces, error)
+}
+
+// detectMultiple returns the faces found by detector, NoFaceErr if nothing found
+func detectMultiple(ctx context.Context, detector Detector, img image.Image, findLandmarks bool, minSize int) (Faces, error) {
+	faces, err := detector.Detect(ctx, img, findLandmarks, minSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(faces) == 0 {
+		return nil, NewError(NoFaceErr, "no face detected")
+	}
+	return faces, nil
+}
+
+// detectSingle returns the best scored face found by detector
+func detectSingle(ctx context.Context, detector Detector, img image.Image, findLandmarks bool, minSize int) (face Face, err error) {
+	faces, err := detectMultiple(ctx, detector, img, findLandmarks, minSize)
+	if err != nil {
+		return face, err
+	}
+	face = faces[0]
+	for _, f := range faces[1:] {
+		if f.Score > face.Score {
+			face = f
+		}
+	}
+	if face.Area.Col == 0 || face.Area.Row == 0 {
+		return face, NewError(NoFaceErr, "no face detected")
+	}
+	return face, nil
+}
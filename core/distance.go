@@ -1,14 +1,46 @@
 package core
 
-import "math"
+import (
+	"fmt"
+	"math"
+)
 
 // EuclideanDistance calculate euclidean distance between to vectors
-func EuclideanDistance(face1 []float32, face2 []float32) float64 {
+func EuclideanDistance(face1 []float32, face2 []float32) (float64, error) {
+	if err := checkDimension(face1, face2); err != nil {
+		return 0, err
+	}
 	var dist float64
 	// TODO use more efficient implementation
 	// either with TF or some go library, and batch processing
-	for k := 0; k < 512; k++ {
+	for k := range face1 {
 		dist += math.Pow(float64(face1[k]-face2[k]), 2)
 	}
-	return math.Sqrt(dist)
+	return math.Sqrt(dist), nil
+}
+
+// L2Normalize returns the vector scaled to unit length
+func L2Normalize(embedding []float32) []float32 {
+	var norm float64
+	for _, v := range embedding {
+		norm += float64(v) * float64(v)
+	}
+	norm = math.Sqrt(norm)
+	ret := make([]float32, len(embedding))
+	if norm < 1e-12 {
+		copy(ret, embedding)
+		return ret
+	}
+	for i, v := range embedding {
+		ret[i] = float32(float64(v) / norm)
+	}
+	return ret
+}
+
+// checkDimension returns DimensionMismatchErr if two vectors have different dimensions
+func checkDimension(face1 []float32, face2 []float32) error {
+	if len(face1) != len(face2) {
+		return NewError(DimensionMismatchErr, fmt.Sprintf("embedding dimensions mismatch, %d != %d", len(face1), len(face2)))
+	}
+	return nil
 }
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEuclideanDistance(t *testing.T) {
+	t.Run("distance", func(t *testing.T) {
+		dist, err := EuclideanDistance([]float32{0, 0, 0}, []float32{1, 2, 2})
+		assert.Nil(t, err)
+		assert.InDelta(t, 3, dist, 1e-6)
+	})
+	t.Run("dimension mismatch", func(t *testing.T) {
+		_, err := EuclideanDistance([]float32{0, 0, 0}, []float32{1, 2})
+		if assert.Error(t, err) {
+			assert.Equal(t, DimensionMismatchErr, err.(Error).Code)
+		}
+	})
+}
+
+func TestL2Normalize(t *testing.T) {
+	t.Run("unit length", func(t *testing.T) {
+		v := L2Normalize([]float32{3, 4})
+		assert.InDelta(t, 0.6, v[0], 1e-6)
+		assert.InDelta(t, 0.8, v[1], 1e-6)
+		var norm float64
+		for _, x := range v {
+			norm += float64(x * x)
+		}
+		assert.InDelta(t, 1, math.Sqrt(norm), 1e-6)
+	})
+	t.Run("zero vector", func(t *testing.T) {
+		assert.Equal(t, []float32{0, 0}, L2Normalize([]float32{0, 0}))
+	})
+}
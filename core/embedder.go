@@ -0,0 +1,251 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/bububa/facenet/imageutil"
+)
+
+// Embedder represents a face embedding backend
+type Embedder interface {
+	// Embed returns the embedding of a face crop
+	Embed(img image.Image) ([]float32, error)
+}
+
+// BatchEmbedder represents an Embedder which is able to embed multiple face crops in one inference
+type BatchEmbedder interface {
+	Embedder
+	// EmbedBatch returns the embeddings of face crops in the same order
+	EmbedBatch(imgs []image.Image) ([][]float32, error)
+}
+
+// modelLoader represents an Embedder which loads its model before use, e.g. Net
+type modelLoader interface {
+	LoadModel() error
+}
+
+// EmbedBatch returns the embeddings of face crops, using one inference if embedder is a BatchEmbedder
+func EmbedBatch(embedder Embedder, imgs []image.Image) ([][]float32, error) {
+	return EmbedBatchContext(context.Background(), embedder, imgs)
+}
+
+// EmbedBatchContext is EmbedBatch checking ctx before each inference
+func EmbedBatchContext(ctx context.Context, embedder Embedder, imgs []image.Image) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if batcher, ok := embedder.(BatchEmbedder); ok {
+		return batcher.EmbedBatch(imgs)
+	}
+	ret := make([][]float32, 0, len(imgs))
+	for _, img := range imgs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		embedding, err := embedder.Embed(img)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, embedding)
+	}
+	return ret, nil
+}
+
+// faceThumb crops a face from src, aligned by eyes if enabled and both eyes were found
+func faceThumb(src image.Image, face Face, opts *detectOptions) image.Image {
+	if opts.align {
+		if left, right, ok := face.EyesPoints(); ok {
+			return imageutil.AlignedThumb(src, left, right, CropSize)
+		}
+	}
+	return imageutil.Thumb(src, face.CropArea(), CropSize)
+}
+
+// embedFaces crops faces from src and fills their embeddings.
+// Failures of bad crops are recorded per face, ctx errors and model errors are returned.
+func embedFaces(ctx context.Context, embedder Embedder, src image.Image, faces Faces, opts *detectOptions) error {
+	if loader, ok := embedder.(modelLoader); ok {
+		if err := loader.LoadModel(); err != nil {
+			return err
+		}
+	}
+	indices := make([]int, 0, len(faces))
+	thumbs := make([]image.Image, 0, len(faces))
+	for i, f := range faces {
+		if f.Area.Col == 0 || f.Area.Row == 0 {
+			faces[i].embeddingErr = NewError(EmbeddingFailedErr, "embedding failed, invalid face area")
+			continue
+		}
+		thumb := faceThumb(src, f, opts)
+		if opts.scoreQuality() {
+			quality := NewQuality(f, thumb)
+			faces[i].Quality = &quality
+		}
+		indices = append(indices, i)
+		thumbs = append(thumbs, thumb)
+	}
+	if len(thumbs) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if batcher, ok := embedder.(BatchEmbedder); ok {
+		embeddings, err := batcher.EmbedBatch(thumbs)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err == nil {
+			if len(embeddings) != len(thumbs) {
+				return NewError(InferenceFailedErr, fmt.Sprintf("inference failed, expect %d embeddings, got %d", len(thumbs), len(embeddings)))
+			}
+			for i, idx := range indices {
+				faces[idx].Embeddings = [][]float32{embeddings[i]}
+			}
+			return nil
+		}
+		if isModelErr(err) {
+			return err
+		}
+		// One bad crop fails the whole batch, crops are embedded one by one so that only bad ones are marked.
+	}
+	for i, idx := range indices {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		embedding, err := embedder.Embed(thumbs[i])
+		if err != nil {
+			if isModelErr(err) {
+				return err
+			}
+			faces[idx].embeddingErr = NewError(EmbeddingFailedErr, fmt.Sprintf("embedding failed, %v", err))
+			continue
+		}
+		faces[idx].Embeddings = [][]float32{embedding}
+	}
+	return nil
+}
+
+// isModelErr returns true if err is a failure of the model itself, which fails the embedding of any crop
+func isModelErr(err error) bool {
+	var e Error
+	return errors.As(err, &e) && e.Code == InferenceFailedErr
+}
+
+// DetectMultiple detect multiple faces try to use different minSize and embed them with embedder
+func DetectMultiple(embedder Embedder, img image.Image, minSize int, opts ...DetectOption) (faces Faces, err error) {
+	return DetectMultipleContext(context.Background(), embedder, img, minSize, opts...)
+}
+
+// DetectMultipleContext is DetectMultiple returning ctx.Err() once ctx is done
+func DetectMultipleContext(ctx context.Context, embedder Embedder, img image.Image, minSize int, opts ...DetectOption) (faces Faces, err error) {
+	options := newDetectOptions(opts...)
+	src := options.source(img)
+	faces, err = options.extractMultiple(ctx, src, minSize)
+
+	if err != nil {
+		return faces, err
+	}
+	faces.setTransform(img, src)
+
+	if err := embedFaces(ctx, embedder, src, faces, options); err != nil {
+		return nil, err
+	}
+	return faces, nil
+}
+
+// DetectSingle detect single face try to use different minSize and embed it with embedder
+func DetectSingle(embedder Embedder, img image.Image, minSize int, opts ...DetectOption) (face Face, err error) {
+	return DetectSingleContext(context.Background(), embedder, img, minSize, opts...)
+}
+
+// DetectSingleContext is DetectSingle returning ctx.Err() once ctx is done
+func DetectSingleContext(ctx context.Context, embedder Embedder, img image.Image, minSize int, opts ...DetectOption) (face Face, err error) {
+	options := newDetectOptions(opts...)
+	src := options.source(img)
+	face, err = options.extractSingle(ctx, src, minSize)
+
+	if err != nil {
+		return face, err
+	}
+	face.Transform = newTransform(img, src)
+
+	faces := Faces{face}
+	if err := embedFaces(ctx, embedder, src, faces, options); err != nil {
+		return face, err
+	}
+	return faces[0], nil
+}
+
+// Detect runs the detection algorithm over the provided source image and embed faces with embedder.
+func Detect(embedder Embedder, img image.Image, minSize int, expected int, opts ...DetectOption) (faces Faces, err error) {
+	return DetectContext(context.Background(), embedder, img, minSize, expected, opts...)
+}
+
+// DetectContext is Detect returning ctx.Err() once ctx is done
+func DetectContext(ctx context.Context, embedder Embedder, img image.Image, minSize int, expected int, opts ...DetectOption) (faces Faces, err error) {
+	options := newDetectOptions(opts...)
+	src := options.source(img)
+	faces, err = options.extract(ctx, src, minSize)
+
+	if err != nil {
+		return faces, err
+	}
+	faces.setTransform(img, src)
+
+	if c := len(faces); c == 0 || expected > 0 && c == expected {
+		return faces, nil
+	}
+
+	if err := embedFaces(ctx, embedder, src, faces, options); err != nil {
+		return nil, err
+	}
+
+	return faces, nil
+}
+
+// DetectTrainingFace detect single face and embed it with embedder, faces failed to embed or below the minimum quality are rejected
+func DetectTrainingFace(embedder Embedder, img image.Image, minSize int, opts ...DetectOption) (face Face, err error) {
+	return DetectTrainingFaceContext(context.Background(), embedder, img, minSize, opts...)
+}
+
+// DetectTrainingFaceContext is DetectTrainingFace returning ctx.Err() once ctx is done
+func DetectTrainingFaceContext(ctx context.Context, embedder Embedder, img image.Image, minSize int, opts ...DetectOption) (face Face, err error) {
+	face, err = DetectSingleContext(ctx, embedder, img, minSize, opts...)
+	if err != nil {
+		return face, err
+	}
+	if err := face.EmbeddingErr(); err != nil {
+		return face, err
+	}
+	if err := newDetectOptions(opts...).checkQuality(face); err != nil {
+		return face, err
+	}
+	return face, nil
+}
+
+// Train train images with label defined by embedder
+func Train(embedder Embedder, label string, images []image.Image, minSize int, opts ...DetectOption) (*Person, error) {
+	return TrainContext(context.Background(), embedder, label, images, minSize, opts...)
+}
+
+// TrainContext is Train returning ctx.Err() once ctx is done
+func TrainContext(ctx context.Context, embedder Embedder, label string, images []image.Image, minSize int, opts ...DetectOption) (*Person, error) {
+	person := &Person{
+		Name:       label,
+		Embeddings: make([]*Person_Embedding, 0, len(images)),
+	}
+	for _, img := range images {
+		face, err := DetectTrainingFaceContext(ctx, embedder, img, minSize, opts...)
+		if err != nil {
+			return person, err
+		}
+		person.Embeddings = append(person.Embeddings, &Person_Embedding{
+			Value: face.Embeddings[0],
+		})
+	}
+	return person, nil
+}
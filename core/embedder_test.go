@@ -0,0 +1,239 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"image"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEmbedder returns the mean color of a face crop as a deterministic embedding
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embed(img image.Image) ([]float32, error) {
+	var r, g, b float32
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cr, cg, cb, _ := img.At(x, y).RGBA()
+			r += float32(cr >> 8)
+			g += float32(cg >> 8)
+			b += float32(cb >> 8)
+		}
+	}
+	count := float32(bounds.Dx() * bounds.Dy())
+	return []float32{r / count, g / count, b / count}, nil
+}
+
+func loadTestImage(t testing.TB, fileName string) image.Image {
+	fn, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fn.Close()
+	img, _, err := image.Decode(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestDetectMultiple(t *testing.T) {
+	img := loadTestImage(t, "../testdata/18.jpg")
+
+	faces, err := DetectMultiple(fakeEmbedder{}, img, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, 2, faces.Count())
+	for _, f := range faces {
+		if assert.Len(t, f.Embeddings, 1) {
+			assert.Len(t, f.Embeddings[0], 3)
+		}
+	}
+}
+
+func TestDetectSingle(t *testing.T) {
+	img := loadTestImage(t, "../testdata/1.jpg")
+
+	a, err := DetectSingle(fakeEmbedder{}, img, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := DetectSingle(fakeEmbedder{}, img, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, a.Embeddings, b.Embeddings)
+}
+
+func TestDetectMultipleContext(t *testing.T) {
+	img := loadTestImage(t, "../testdata/18.jpg")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := DetectMultipleContext(ctx, fakeEmbedder{}, img, 20)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// cancelEmbedder cancels its context after the first inference
+type cancelEmbedder struct {
+	fakeEmbedder
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (e *cancelEmbedder) Embed(img image.Image) ([]float32, error) {
+	e.calls++
+	e.cancel()
+	return e.fakeEmbedder.Embed(img)
+}
+
+func TestEmbedBatchContext(t *testing.T) {
+	thumb := testThumb(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	embedder := &cancelEmbedder{cancel: cancel}
+
+	_, err := EmbedBatchContext(ctx, embedder, []image.Image{thumb, thumb, thumb})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, embedder.calls)
+}
+
+// brokenEmbedder fails as a broken model does
+type brokenEmbedder struct{}
+
+func (brokenEmbedder) Embed(img image.Image) ([]float32, error) {
+	return nil, NewError(InferenceFailedErr, "inference failed, no output")
+}
+
+// badCropEmbedder fails to embed black crops, and whole batches holding one
+type badCropEmbedder struct {
+	fakeEmbedder
+}
+
+func (e badCropEmbedder) Embed(img image.Image) ([]float32, error) {
+	embedding, err := e.fakeEmbedder.Embed(img)
+	if err == nil && embedding[0]+embedding[1]+embedding[2] == 0 {
+		return nil, errors.New("black crop")
+	}
+	return embedding, err
+}
+
+func (e badCropEmbedder) EmbedBatch(imgs []image.Image) ([][]float32, error) {
+	ret := make([][]float32, 0, len(imgs))
+	for _, img := range imgs {
+		embedding, err := e.Embed(img)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, embedding)
+	}
+	return ret, nil
+}
+
+// countingEmbedder counts the crops embedded by an Embedder which is not a BatchEmbedder
+type countingEmbedder struct {
+	embedder Embedder
+	calls    int
+}
+
+func (e *countingEmbedder) Embed(img image.Image) ([]float32, error) {
+	e.calls++
+	return e.embedder.Embed(img)
+}
+
+// unloadableEmbedder fails to load its model
+type unloadableEmbedder struct {
+	fakeEmbedder
+}
+
+func (unloadableEmbedder) LoadModel() error {
+	return errors.New("model not found")
+}
+
+func TestEmbedFaces(t *testing.T) {
+	img := loadTestImage(t, "../testdata/2.jpg")
+	bounds := img.Bounds()
+	newFaces := func() Faces {
+		return Faces{
+			{Rows: bounds.Dy(), Cols: bounds.Dx(), Area: NewArea("face", bounds.Dy()/2, bounds.Dx()/2, bounds.Dx()/2)},
+			{Rows: bounds.Dy(), Cols: bounds.Dx()},
+		}
+	}
+	opts := newDetectOptions()
+
+	t.Run("embedded", func(t *testing.T) {
+		faces := newFaces()
+		assert.NoError(t, embedFaces(context.Background(), fakeEmbedder{}, img, faces, opts))
+		assert.NoError(t, faces[0].EmbeddingErr())
+		assert.Len(t, faces[0].Embeddings, 1)
+		if assert.Error(t, faces[1].EmbeddingErr()) {
+			assert.Equal(t, EmbeddingFailedErr, faces[1].EmbeddingErr().(Error).Code)
+		}
+		assert.Empty(t, faces[1].Embeddings)
+		assert.Nil(t, faces[0].Quality)
+	})
+
+	t.Run("quality", func(t *testing.T) {
+		faces := newFaces()
+		assert.NoError(t, embedFaces(context.Background(), fakeEmbedder{}, img, faces, newDetectOptions(WithQuality(true))))
+		assert.NotNil(t, faces[0].Quality)
+	})
+
+	t.Run("model failed", func(t *testing.T) {
+		faces := newFaces()
+		err := embedFaces(context.Background(), brokenEmbedder{}, img, faces, opts)
+		if assert.Error(t, err) {
+			assert.Equal(t, InferenceFailedErr, err.(Error).Code)
+		}
+	})
+
+	// left half black, right half white
+	src := image.NewGray(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 100; x < 200; x++ {
+			src.Pix[src.PixOffset(x, y)] = 255
+		}
+	}
+	newCropFaces := func() Faces {
+		return Faces{
+			{Rows: 100, Cols: 200, Area: NewArea("face", 50, 150, 40)},
+			{Rows: 100, Cols: 200, Area: NewArea("face", 50, 50, 40)},
+		}
+	}
+	assertBadCrop := func(t *testing.T, faces Faces) {
+		assert.NoError(t, faces[0].EmbeddingErr())
+		assert.Len(t, faces[0].Embeddings, 1)
+		if assert.Error(t, faces[1].EmbeddingErr()) {
+			assert.Equal(t, EmbeddingFailedErr, faces[1].EmbeddingErr().(Error).Code)
+		}
+		assert.Empty(t, faces[1].Embeddings)
+	}
+
+	t.Run("bad crop", func(t *testing.T) {
+		faces := newCropFaces()
+		assert.NoError(t, embedFaces(context.Background(), badCropEmbedder{}, src, faces, opts))
+		assertBadCrop(t, faces)
+	})
+
+	t.Run("bad crop embedded once", func(t *testing.T) {
+		faces := newCropFaces()
+		embedder := &countingEmbedder{embedder: badCropEmbedder{}}
+		assert.NoError(t, embedFaces(context.Background(), embedder, src, faces, opts))
+		assertBadCrop(t, faces)
+		assert.Equal(t, len(faces), embedder.calls)
+	})
+
+	t.Run("load failed", func(t *testing.T) {
+		faces := newFaces()
+		assert.EqualError(t, embedFaces(context.Background(), unloadableEmbedder{}, img, faces, opts), "model not found")
+		for _, f := range faces {
+			assert.Empty(t, f.Embeddings)
+		}
+	})
+}
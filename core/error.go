@@ -24,6 +24,16 @@ const (
 	NothingMatchErr
 	// UnknownClassifierErr represents met an unknown classifier in saved db
 	UnknownClassifierErr
+	// DimensionMismatchErr represents embeddings have different dimensions
+	DimensionMismatchErr
+	// EmbeddingFailedErr represents the embedding of a detected face failed
+	EmbeddingFailedErr
+	// InvalidCascadeErr represents a malformed pigo cascade file
+	InvalidCascadeErr
+	// LowQualityErr represents a face below the minimum quality
+	LowQualityErr
+	// InvalidManifestErr represents a malformed model manifest
+	InvalidManifestErr
 )
 
 // Error custom error object
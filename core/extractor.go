@@ -1,58 +1,19 @@
 package core
 
 import (
-	// use embed
-	_ "embed"
-
 	// use jpeg image
 	_ "image/jpeg"
 
+	"context"
 	"fmt"
 	"image"
+	"math"
 	"sort"
 	"sync"
 
 	pigo "github.com/esimov/pigo/core"
 )
 
-//go:embed cascade/facefinder
-var cascadeFile []byte
-
-//go:embed cascade/puploc
-var puplocFile []byte
-
-var (
-	classifier *pigo.Pigo
-	plc        *pigo.PuplocCascade
-	flpcs      map[string][]*FlpCascade
-)
-
-func init() {
-	var err error
-
-	p := pigo.NewPigo()
-	// Unpack the binary file. This will return the number of cascade trees,
-	// the tree depth, the threshold and the prediction from tree's leaf nodes.
-	classifier, err = p.Unpack(cascadeFile)
-
-	if err != nil {
-		panic(err)
-	}
-
-	pl := pigo.NewPuplocCascade()
-	plc, err = pl.UnpackCascade(puplocFile)
-
-	if err != nil {
-		panic(err)
-	}
-
-	flpcs, err = ReadCascadeDir(pl, "cascade/lps")
-
-	if err != nil {
-		panic(err)
-	}
-}
-
 var (
 	eyeCascades   = []string{"lp46", "lp44", "lp42", "lp38", "lp312"}
 	mouthCascades = []string{"lp93", "lp84", "lp82", "lp81"}
@@ -60,18 +21,41 @@ var (
 
 // Extractor struct contains Pigo face detector general settings.
 type Extractor struct {
-	minSize            int
-	angle              float64
-	shiftFactor        float64
-	scaleFactor        float64
-	iouThreshold       float64
-	scoreThreshold     float32
-	perturb            int
-	landmarkCoordsPool *sync.Pool
+	minSize        int
+	angle          float64
+	angles         []float64
+	shiftFactor    float64
+	scaleFactor    float64
+	iouThreshold   float64
+	scoreThreshold float32
+	perturb        int
+	cascades       *Cascades
+}
+
+// NewExtractor returns a new Extractor with default settings
+func NewExtractor(minSize int, opts ...ExtractorOption) *Extractor {
+	d := &Extractor{
+		minSize:        minSize,
+		angle:          0.0,
+		shiftFactor:    0.1,
+		scaleFactor:    1.1,
+		iouThreshold:   0.2,
+		scoreThreshold: float32(ScoreThreshold),
+		perturb:        63,
+	}
+	for _, opt := range opts {
+		opt.apply(d)
+	}
+	return d
 }
 
 // TrySizeExtractMultiple extract multiple faces with different minSize
-func TrySizeExtractMultiple(img image.Image, findLandmarks bool, minSize int) (faces Faces, err error) {
+func TrySizeExtractMultiple(img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (faces Faces, err error) {
+	return TrySizeExtractMultipleContext(context.Background(), img, findLandmarks, minSize, opts...)
+}
+
+// TrySizeExtractMultipleContext extract multiple faces with different minSize, returns ctx.Err() once ctx is done
+func TrySizeExtractMultipleContext(ctx context.Context, img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (faces Faces, err error) {
 	maxSize := MaxImageSize
 	w := img.Bounds().Max.X
 	h := img.Bounds().Max.Y
@@ -82,13 +66,19 @@ func TrySizeExtractMultiple(img image.Image, findLandmarks bool, minSize int) (f
 	}
 	scales := maxSize / minSize
 	for i := 1; i <= scales; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		size := minSize * i
-		faces, err = Extract(img, findLandmarks, size)
+		faces, err = ExtractContext(ctx, img, findLandmarks, size, opts...)
 		if err != nil || len(faces) == 0 {
 			continue
 		}
 		break
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if len(faces) == 0 {
 		return nil, NewError(NoFaceErr, "no face detected")
 	}
@@ -96,7 +86,12 @@ func TrySizeExtractMultiple(img image.Image, findLandmarks bool, minSize int) (f
 }
 
 // TrySizeExtractSingle extract single face with different minSize
-func TrySizeExtractSingle(img image.Image, findLandmarks bool, minSize int) (face Face, err error) {
+func TrySizeExtractSingle(img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (face Face, err error) {
+	return TrySizeExtractSingleContext(context.Background(), img, findLandmarks, minSize, opts...)
+}
+
+// TrySizeExtractSingleContext extract single face with different minSize, returns ctx.Err() once ctx is done
+func TrySizeExtractSingleContext(ctx context.Context, img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (face Face, err error) {
 	maxSize := MaxImageSize
 	w := img.Bounds().Max.X
 	h := img.Bounds().Max.Y
@@ -108,13 +103,19 @@ func TrySizeExtractSingle(img image.Image, findLandmarks bool, minSize int) (fac
 	scales := maxSize / minSize
 	var list Faces
 	for i := 1; i <= scales; i++ {
+		if err := ctx.Err(); err != nil {
+			return face, err
+		}
 		size := minSize * i
-		faces, err := Extract(img, findLandmarks, size)
+		faces, err := ExtractContext(ctx, img, findLandmarks, size, opts...)
 		if err != nil || len(faces) != 1 {
 			continue
 		}
 		list = append(list, faces[0])
 	}
+	if err := ctx.Err(); err != nil {
+		return face, err
+	}
 	if len(list) == 0 {
 		return face, NewError(NoFaceErr, "no face detected")
 	}
@@ -128,29 +129,51 @@ func TrySizeExtractSingle(img image.Image, findLandmarks bool, minSize int) (fac
 	return list[0], nil
 }
 
+// ExtractMultiple extract multiple faces in one pyramid pass over all face sizes from minSize.
+// Faces are selected by score, IoU clustering and QualityThreshold instead of rerunning the detection per minSize.
+func ExtractMultiple(img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (faces Faces, err error) {
+	return ExtractMultipleContext(context.Background(), img, findLandmarks, minSize, opts...)
+}
+
+// ExtractMultipleContext extract multiple faces in one pyramid pass, returns ctx.Err() if ctx is done
+func ExtractMultipleContext(ctx context.Context, img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (faces Faces, err error) {
+	return detectMultiple(ctx, NewExtractor(minSize, opts...), img, findLandmarks, minSize)
+}
+
+// ExtractSingle extract the best scored face in one pyramid pass over all face sizes from minSize
+func ExtractSingle(img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (face Face, err error) {
+	return ExtractSingleContext(context.Background(), img, findLandmarks, minSize, opts...)
+}
+
+// ExtractSingleContext extract the best scored face in one pyramid pass, returns ctx.Err() if ctx is done
+func ExtractSingleContext(ctx context.Context, img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (face Face, err error) {
+	return detectSingle(ctx, NewExtractor(minSize, opts...), img, findLandmarks, minSize)
+}
+
 // Extract runs the detection algorithm over the provided source image.
-func Extract(img image.Image, findLandmarks bool, minSize int) (faces Faces, err error) {
+func Extract(img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (faces Faces, err error) {
+	return ExtractContext(context.Background(), img, findLandmarks, minSize, opts...)
+}
+
+// ExtractContext runs the detection algorithm over the provided source image, returns ctx.Err() if ctx is done.
+func ExtractContext(ctx context.Context, img image.Image, findLandmarks bool, minSize int, opts ...ExtractorOption) (faces Faces, err error) {
+	return NewExtractor(minSize, opts...).Detect(ctx, img, findLandmarks, minSize)
+}
+
+// Detect implements Detector interface, runs one pyramid pass over all face sizes from minSize
+func (d *Extractor) Detect(ctx context.Context, img image.Image, findLandmarks bool, minSize int) (faces Faces, err error) {
+	if err := ctx.Err(); err != nil {
+		return faces, err
+	}
 
 	if minSize < 20 {
 		minSize = 20
 	}
 
-	extractor := &Extractor{
-		minSize:        minSize,
-		angle:          0.0,
-		shiftFactor:    0.1,
-		scaleFactor:    1.1,
-		iouThreshold:   0.2,
-		scoreThreshold: float32(ScoreThreshold),
-		perturb:        63,
-		landmarkCoordsPool: &sync.Pool{
-			New: func() interface{} {
-				return make([]Area, 0, len(flpcs))
-			},
-		},
-	}
+	extractor := *d
+	extractor.minSize = minSize
 
-	det, params, err := extractor.Extract(img)
+	det, params, err := extractor.detect(img)
 
 	if err != nil {
 		return faces, err
@@ -160,11 +183,43 @@ func Extract(img image.Image, findLandmarks bool, minSize int) (faces Faces, err
 		return faces, NewError(NoFaceErr, "no face detected")
 	}
 
-	return extractor.Faces(det, params, findLandmarks)
+	return extractor.faces(det, params, findLandmarks)
+}
+
+// detection represents a pigo detection with the angle it was detected at
+type detection struct {
+	pigo.Detection
+	angle float64
 }
 
 // Extract runs the detection algorithm over the provided source image.
 func (d *Extractor) Extract(img image.Image) (faces []pigo.Detection, params pigo.CascadeParams, err error) {
+	det, params, err := d.detect(img)
+	if err != nil {
+		return faces, params, err
+	}
+	faces = make([]pigo.Detection, 0, len(det))
+	for _, face := range det {
+		faces = append(faces, face.Detection)
+	}
+	return faces, params, nil
+}
+
+// loadCascades returns the cascades of the extractor, the embedded ones by default
+func (d *Extractor) loadCascades() (*Cascades, error) {
+	if d.cascades != nil {
+		return d.cascades, nil
+	}
+	return DefaultCascades()
+}
+
+// detect runs the detection algorithm at every angle over the provided source image.
+func (d *Extractor) detect(img image.Image) (faces []detection, params pigo.CascadeParams, err error) {
+	cascades, err := d.loadCascades()
+	if err != nil {
+		return faces, params, err
+	}
+
 	src := pigo.ImgToNRGBA(img)
 
 	pixels := pigo.RgbToGrayscale(src)
@@ -198,18 +253,88 @@ func (d *Extractor) Extract(img image.Image) (faces []pigo.Detection, params pig
 
 	//log.Printf("faces: image size %dx%d, face size min %d, max %d\n", cols, rows, params.MinSize, params.MaxSize)
 
-	// Run the classifier over the obtained leaf nodes and return the Face results.
-	// The result contains quadruplets representing the row, column, scale and Face score.
-	faces = classifier.RunCascade(params, d.angle)
+	angles := d.angles
+	if len(angles) == 0 {
+		angles = []float64{d.angle}
+	}
+
+	faces = make([]detection, 0)
+	for _, angle := range angles {
+		// Run the classifier over the obtained leaf nodes and return the Face results.
+		// The result contains quadruplets representing the row, column, scale and Face score.
+		det := cascades.facefinder.RunCascade(params, angle)
+
+		// Calculate the intersection over union (IoU) of two clusters.
+		for _, face := range cascades.facefinder.ClusterDetections(det, d.iouThreshold) {
+			faces = append(faces, detection{Detection: face, angle: angle})
+		}
+	}
 
-	// Calculate the intersection over union (IoU) of two clusters.
-	faces = classifier.ClusterDetections(faces, d.iouThreshold)
+	if len(angles) > 1 {
+		faces = mergeDetections(faces, d.iouThreshold)
+	}
 
 	return faces, params, nil
 }
 
+// mergeDetections clusters detections found at different angles, keeping the best scored one of each cluster.
+func mergeDetections(det []detection, iouThreshold float64) []detection {
+	sort.SliceStable(det, func(i, j int) bool {
+		return det[i].Q > det[j].Q
+	})
+	assignments := make([]bool, len(det))
+	clusters := make([]detection, 0, len(det))
+	for i := range det {
+		if assignments[i] {
+			continue
+		}
+		for j := i + 1; j < len(det); j++ {
+			if !assignments[j] && detectionIoU(det[i].Detection, det[j].Detection) > iouThreshold {
+				assignments[j] = true
+			}
+		}
+		clusters = append(clusters, det[i])
+	}
+	return clusters
+}
+
+// detectionIoU returns the intersection over union of two square detections
+func detectionIoU(det1, det2 pigo.Detection) float64 {
+	r1, c1, s1 := float64(det1.Row), float64(det1.Col), float64(det1.Scale)
+	r2, c2, s2 := float64(det2.Row), float64(det2.Col), float64(det2.Scale)
+
+	overRow := math.Max(0, math.Min(r1+s1/2, r2+s2/2)-math.Max(r1-s1/2, r2-s2/2))
+	overCol := math.Max(0, math.Min(c1+s1/2, c2+s2/2)-math.Max(c1-s1/2, c2-s2/2))
+
+	return overRow * overCol / (s1*s1 + s2*s2 - overRow*overCol)
+}
+
+// rotateOffset rotates an offset relative to the face center by the detection angle
+func rotateOffset(row, col float64, angle float64) (int, int) {
+	if angle == 0 {
+		return int(row), int(col)
+	}
+	sin, cos := math.Sincos(2 * math.Pi * angle)
+	return int(cos*row - sin*col), int(sin*row + cos*col)
+}
+
 // Faces adds landmark coordinates to detected faces and returns the results.
 func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findLandmarks bool) (results Faces, err error) {
+	faces := make([]detection, 0, len(det))
+	for _, face := range det {
+		faces = append(faces, detection{Detection: face, angle: d.angle})
+	}
+	return d.faces(faces, params, findLandmarks)
+}
+
+// faces adds landmark coordinates to detected faces and returns the results.
+func (d *Extractor) faces(det []detection, params pigo.CascadeParams, findLandmarks bool) (results Faces, err error) {
+	cascades, err := d.loadCascades()
+	if err != nil {
+		return results, err
+	}
+	plc, flpcs := cascades.puploc, cascades.landmarks
+
 	// Sort results by size.
 	sort.Slice(det, func(i, j int) bool {
 		return det[i].Scale > det[j].Scale
@@ -221,7 +346,6 @@ func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findL
 			return new(pigo.Puploc)
 		},
 	}
-	eyesCoords := make([]Area, 0, 2)
 	facePool := &sync.Pool{
 		New: func() interface{} {
 			return new(Face)
@@ -234,9 +358,9 @@ func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findL
 			continue
 		}
 
-		eyesCoords = eyesCoords[:0]
-		landmarkCoords := d.landmarkCoordsPool.Get().([]Area)
-		landmarkCoords = landmarkCoords[:0]
+		// Coordinates are owned by the face, they must not be shared between faces.
+		eyesCoords := make([]Area, 0, 2)
+		var landmarkCoords []Area
 		puploc := puplocPool.Get().(*pigo.Puploc)
 
 		faceCoord := NewArea(
@@ -247,14 +371,15 @@ func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findL
 		)
 
 		// Detect additional face landmarks?
-		if face.Scale > 50 && findLandmarks {
+		if face.Scale > 50 && findLandmarks && plc != nil {
 			// Find left eye.
-			puploc.Row = face.Row - int(0.075*float32(face.Scale))
-			puploc.Col = face.Col - int(0.175*float32(face.Scale))
+			dRow, dCol := rotateOffset(-0.075*float64(face.Scale), -0.175*float64(face.Scale), face.angle)
+			puploc.Row = face.Row + dRow
+			puploc.Col = face.Col + dCol
 			puploc.Scale = float32(face.Scale) * 0.25
 			puploc.Perturbs = d.perturb
 
-			leftEye := plc.RunDetector(*puploc, params.ImageParams, d.angle, false)
+			leftEye := plc.RunDetector(*puploc, params.ImageParams, face.angle, false)
 
 			if leftEye.Row > 0 && leftEye.Col > 0 {
 				eyesCoords = append(eyesCoords, NewArea(
@@ -266,12 +391,13 @@ func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findL
 			}
 
 			// Find right eye.
-			puploc.Row = face.Row - int(0.075*float32(face.Scale))
-			puploc.Col = face.Col + int(0.185*float32(face.Scale))
+			dRow, dCol = rotateOffset(-0.075*float64(face.Scale), 0.185*float64(face.Scale), face.angle)
+			puploc.Row = face.Row + dRow
+			puploc.Col = face.Col + dCol
 			puploc.Scale = float32(face.Scale) * 0.25
 			puploc.Perturbs = d.perturb
 
-			rightEye := plc.RunDetector(*puploc, params.ImageParams, d.angle, false)
+			rightEye := plc.RunDetector(*puploc, params.ImageParams, face.angle, false)
 
 			if rightEye.Row > 0 && rightEye.Col > 0 {
 				eyesCoords = append(eyesCoords, NewArea(
@@ -331,9 +457,8 @@ func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findL
 				}
 			}
 
-			flpc := flpcs["lp84"][0]
-
-			if flpc != nil {
+			if lp84 := flpcs["lp84"]; len(lp84) > 0 && lp84[0] != nil {
+				flpc := lp84[0]
 				flp := flpc.GetLandmarkPoint(leftEye, rightEye, params.ImageParams, d.perturb, true)
 				if flp.Row > 0 && flp.Col > 0 {
 					landmarkCoords = append(landmarkCoords, NewArea(
@@ -354,12 +479,12 @@ func (d *Extractor) Faces(det []pigo.Detection, params pigo.CascadeParams, findL
 		f.Cols = params.ImageParams.Cols
 		f.Score = int(face.Q)
 		f.Area = faceCoord
+		f.Angle = face.angle
 		f.Eyes = eyesCoords
 		f.Landmarks = landmarkCoords
+		f.Points = NewLandmarks(f.Eyes, f.Landmarks)
 		facePool.Put(fCache)
 
-		d.landmarkCoordsPool.Put(landmarkCoords)
-
 		// Does the face significantly overlap with previous results?
 		if results.Contains(f) {
 			// Ignore face.
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"math"
+)
+
+// ExtractorOption represents Extractor option interface
+type ExtractorOption interface {
+	apply(*Extractor)
+}
+
+type extractorOptionFunc func(*Extractor)
+
+func (fn extractorOptionFunc) apply(d *Extractor) {
+	fn(d)
+}
+
+// WithAngle set the detection angle, 0.0 is upright and 1.0 is a full 2π turn, see DegreesToAngle
+func WithAngle(angle float64) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.angle = angle
+	})
+}
+
+// WithAngles scans the image at multiple detection angles, results are merged by IoU clustering.
+// Use DegreesToAngle to convert angles, e.g. WithAngles(0, DegreesToAngle(30), DegreesToAngle(-30)).
+func WithAngles(angles ...float64) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.angles = angles
+	})
+}
+
+// DegreesToAngle converts degrees into a detection angle in [0, 1), positive degrees match faces rotated counterclockwise
+func DegreesToAngle(degrees float64) float64 {
+	angle := math.Mod(degrees/360, 1)
+	if angle < 0 {
+		angle++
+	}
+	return angle
+}
+
+// AngleToDegrees converts a detection angle into degrees in (-180, 180], positive degrees being counterclockwise
+func AngleToDegrees(angle float64) float64 {
+	degrees := math.Mod(angle, 1) * 360
+	if degrees > 180 {
+		degrees -= 360
+	} else if degrees <= -180 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// WithShiftFactor set the sliding window shift relative to the window size, default 0.1.
+// A coarser shift is faster but may miss faces.
+func WithShiftFactor(shiftFactor float64) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.shiftFactor = shiftFactor
+	})
+}
+
+// WithScaleFactor set the sliding window scale step between pyramid levels, default 1.1
+func WithScaleFactor(scaleFactor float64) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.scaleFactor = scaleFactor
+	})
+}
+
+// WithIoUThreshold set the intersection over union threshold to cluster detections, default 0.2
+func WithIoUThreshold(iouThreshold float64) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.iouThreshold = iouThreshold
+	})
+}
+
+// WithPerturb set the number of perturbations of pupil and landmark detection, default 63
+func WithPerturb(perturb int) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.perturb = perturb
+	})
+}
+
+// WithCascades set the pigo cascades loaded by LoadCascades or LoadCascadeDir, the embedded ones are used by default
+func WithCascades(cascades *Cascades) ExtractorOption {
+	return extractorOptionFunc(func(d *Extractor) {
+		d.cascades = cascades
+	})
+}
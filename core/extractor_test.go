@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	pigo "github.com/esimov/pigo/core"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -144,3 +145,125 @@ func TestExtractOverlap(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestNewExtractor(t *testing.T) {
+	d := NewExtractor(20)
+	assert.Equal(t, 0.1, d.shiftFactor)
+	assert.Equal(t, 1.1, d.scaleFactor)
+	assert.Equal(t, 0.2, d.iouThreshold)
+	assert.Equal(t, 63, d.perturb)
+	assert.Equal(t, 0.0, d.angle)
+
+	d = NewExtractor(20, WithShiftFactor(0.3), WithScaleFactor(1.3), WithIoUThreshold(0.5), WithPerturb(15), WithAngle(0.5))
+	assert.Equal(t, 0.3, d.shiftFactor)
+	assert.Equal(t, 1.3, d.scaleFactor)
+	assert.Equal(t, 0.5, d.iouThreshold)
+	assert.Equal(t, 15, d.perturb)
+	assert.Equal(t, 0.5, d.angle)
+}
+
+func TestMergeDetections(t *testing.T) {
+	det := []detection{
+		{Detection: pigo.Detection{Row: 100, Col: 100, Scale: 80, Q: 10}, angle: 0},
+		{Detection: pigo.Detection{Row: 104, Col: 98, Scale: 84, Q: 30}, angle: DegreesToAngle(30)},
+		{Detection: pigo.Detection{Row: 300, Col: 300, Scale: 60, Q: 20}, angle: DegreesToAngle(-30)},
+	}
+	merged := mergeDetections(det, 0.2)
+	if assert.Len(t, merged, 2) {
+		assert.Equal(t, float32(30), merged[0].Q)
+		assert.Equal(t, DegreesToAngle(30), merged[0].angle)
+		assert.Equal(t, 300, merged[1].Row)
+	}
+}
+
+func TestDegreesToAngle(t *testing.T) {
+	assert.Equal(t, 0.0, DegreesToAngle(0))
+	assert.InDelta(t, 0.25, DegreesToAngle(90), 1e-9)
+	assert.InDelta(t, 0.75, DegreesToAngle(-90), 1e-9)
+	assert.InDelta(t, 0.25, DegreesToAngle(450), 1e-9)
+}
+
+func TestAngleToDegrees(t *testing.T) {
+	assert.Equal(t, 0.0, AngleToDegrees(0))
+	assert.InDelta(t, 90, AngleToDegrees(0.25), 1e-9)
+	assert.InDelta(t, -90, AngleToDegrees(0.75), 1e-9)
+	assert.InDelta(t, 180, AngleToDegrees(0.5), 1e-9)
+	assert.InDelta(t, -30, AngleToDegrees(DegreesToAngle(-30)), 1e-9)
+}
+
+func TestRotateOffset(t *testing.T) {
+	row, col := rotateOffset(-10, 0, 0)
+	assert.Equal(t, []int{-10, 0}, []int{row, col})
+	// the top of a face rotated 90° counterclockwise points left
+	row, col = rotateOffset(-10, 0, DegreesToAngle(90))
+	assert.Equal(t, []int{0, -10}, []int{row, col})
+}
+
+// testdataRecall returns the ratio of faces found by extract in testdata images against netExpected
+func testdataRecall(imgs map[string]image.Image, extract func(img image.Image) (Faces, error)) float64 {
+	var found, total int
+	for name, img := range imgs {
+		expected := netExpected[name]
+		total += expected
+		faces, err := extract(img)
+		if err != nil {
+			continue
+		}
+		if c := faces.Count(); c < expected {
+			found += c
+		} else {
+			found += expected
+		}
+	}
+	return float64(found) / float64(total)
+}
+
+func TestExtractMultiple_Recall(t *testing.T) {
+	for name, expected := range netExpected {
+		img := loadTestImage(t, filepath.Join("../testdata", name))
+		t.Run(name, func(t *testing.T) {
+			// faces found beyond expected are not counted
+			recalled := func(faces Faces, err error) int {
+				if err != nil || faces.Count() < expected {
+					return faces.Count()
+				}
+				return expected
+			}
+			before := recalled(TrySizeExtractMultiple(img, false, 20))
+			after := recalled(ExtractMultiple(img, false, 20))
+			assert.GreaterOrEqual(t, after, before)
+		})
+	}
+}
+
+func BenchmarkExtractMultiple(b *testing.B) {
+	imgs := make(map[string]image.Image, len(netExpected))
+	for name := range netExpected {
+		imgs[name] = loadTestImage(b, filepath.Join("../testdata", name))
+	}
+
+	b.Run("try_size", func(b *testing.B) {
+		extract := func(img image.Image) (Faces, error) {
+			return TrySizeExtractMultiple(img, false, 20)
+		}
+		for i := 0; i < b.N; i++ {
+			for _, img := range imgs {
+				extract(img)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(testdataRecall(imgs, extract), "recall")
+	})
+	b.Run("single_pass", func(b *testing.B) {
+		extract := func(img image.Image) (Faces, error) {
+			return ExtractMultiple(img, false, 20)
+		}
+		for i := 0; i < b.N; i++ {
+			for _, img := range imgs {
+				extract(img)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(testdataRecall(imgs, extract), "recall")
+	})
+}
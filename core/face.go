@@ -2,19 +2,38 @@ package core
 
 import (
 	"encoding/json"
+	"image"
 
 	"github.com/bububa/facenet/imageutil"
 )
 
 // Face represents a face detected.
+// Angle is the detection angle as a fraction of a full turn in [0, 1), positive counterclockwise, see AngleDegrees.
 type Face struct {
 	Rows       int         `json:"rows,omitempty"`
 	Cols       int         `json:"cols,omitempty"`
 	Score      int         `json:"score,omitempty"`
 	Area       Area        `json:"face,omitempty"`
+	Angle      float64     `json:"angle,omitempty"`
 	Eyes       Areas       `json:"eyes,omitempty"`
 	Landmarks  Areas       `json:"landmarks,omitempty"`
+	Points     Landmarks   `json:"points"`
 	Embeddings [][]float32 `json:"embeddings,omitempty"`
+	Quality    *Quality    `json:"quality,omitempty"`
+	Transform  Transform   `json:"transform"`
+	// embeddingErr is set when the face was detected but could not be embedded
+	embeddingErr error
+}
+
+// EmbeddingErr returns the error of embedding the face, nil if embedded successfully
+func (f *Face) EmbeddingErr() error {
+	return f.embeddingErr
+}
+
+// AngleDegrees returns the detection angle in degrees in (-180, 180], positive counterclockwise.
+// Unlike Pose.Roll, it is the rotation of the detection window, not measured from the eyes.
+func (f *Face) AngleDegrees() float64 {
+	return AngleToDegrees(f.Angle)
 }
 
 // Size returns the absolute face size in pixels.
@@ -72,6 +91,22 @@ func (f *Face) EyesMidpoint() Area {
 	}
 }
 
+// EyesPoints returns the absolute eye positions, left being the eye on the left side of the image.
+func (f *Face) EyesPoints() (left image.Point, right image.Point, ok bool) {
+	var foundLeft, foundRight bool
+	for _, eye := range f.Eyes {
+		switch eye.Name {
+		case "eye_l":
+			left = image.Pt(eye.Col, eye.Row)
+			foundLeft = true
+		case "eye_r":
+			right = image.Pt(eye.Col, eye.Row)
+			foundRight = true
+		}
+	}
+	return left, right, foundLeft && foundRight && left != right
+}
+
 // RelativeLandmarks returns relative face areas.
 func (f *Face) RelativeLandmarks() imageutil.Areas {
 	p := f.EyesMidpoint()
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"math"
+	"strings"
+)
+
+// Landmarks represents named face landmarks, left being the side on the left of the image.
+// Landmarks not found are nil.
+type Landmarks struct {
+	LeftEye           *Area `json:"left_eye,omitempty"`
+	RightEye          *Area `json:"right_eye,omitempty"`
+	LeftEyeInner      *Area `json:"left_eye_inner,omitempty"`
+	LeftEyeOuter      *Area `json:"left_eye_outer,omitempty"`
+	RightEyeInner     *Area `json:"right_eye_inner,omitempty"`
+	RightEyeOuter     *Area `json:"right_eye_outer,omitempty"`
+	LeftEyebrowInner  *Area `json:"left_eyebrow_inner,omitempty"`
+	LeftEyebrowOuter  *Area `json:"left_eyebrow_outer,omitempty"`
+	RightEyebrowInner *Area `json:"right_eyebrow_inner,omitempty"`
+	RightEyebrowOuter *Area `json:"right_eyebrow_outer,omitempty"`
+	NoseTip           *Area `json:"nose_tip,omitempty"`
+	MouthLeft         *Area `json:"mouth_left,omitempty"`
+	MouthRight        *Area `json:"mouth_right,omitempty"`
+	UpperLip          *Area `json:"upper_lip,omitempty"`
+	LowerLip          *Area `json:"lower_lip,omitempty"`
+}
+
+// eyebrowDrop minimum height above the pupils of eyebrow points, relative to the distance between eyes
+const eyebrowDrop = 0.15
+
+// NewLandmarks names the eyes and landmarks found by the pigo cascades or MTCNN.
+// The lp84 cascade and its mirror find the mouth corners, lp81 and lp82 the lips and lp93 the nose tip.
+// Points of the pigo eye cascades are named by their position relative to the pupils, so both eyes are needed.
+func NewLandmarks(eyes Areas, landmarks Areas) Landmarks {
+	var ret Landmarks
+	for i, eye := range eyes {
+		switch eye.Name {
+		case "eye_l":
+			ret.LeftEye = &eyes[i]
+		case "eye_r":
+			ret.RightEye = &eyes[i]
+		}
+	}
+
+	var corners, lips, eyeRegion []*Area
+	for i := range landmarks {
+		l := &landmarks[i]
+		switch l.Name {
+		case "nose", "mouth_lp93":
+			ret.NoseTip = l
+		case "mouth_l":
+			ret.MouthLeft = l
+		case "mouth_r":
+			ret.MouthRight = l
+		case "mouth_lp84", "lp84":
+			corners = append(corners, l)
+		case "mouth_lp81", "mouth_lp82":
+			lips = append(lips, l)
+		default:
+			if isEyeCascade(l.Name) {
+				eyeRegion = append(eyeRegion, l)
+			}
+		}
+	}
+
+	// upright returns a point relative to the eyes midpoint with the roll removed, in eye distance units
+	upright := func(p *Area) (float64, float64) {
+		return float64(p.Col), float64(p.Row)
+	}
+	if ret.LeftEye != nil && ret.RightEye != nil {
+		ex, ey := float64(ret.LeftEye.Col+ret.RightEye.Col)/2, float64(ret.LeftEye.Row+ret.RightEye.Row)/2
+		dx, dy := float64(ret.RightEye.Col-ret.LeftEye.Col), float64(ret.RightEye.Row-ret.LeftEye.Row)
+		if dist := math.Hypot(dx, dy); dist > 0 {
+			sin, cos := math.Sincos(-math.Atan2(dy, dx))
+			upright = func(p *Area) (float64, float64) {
+				px, py := float64(p.Col)-ex, float64(p.Row)-ey
+				return (cos*px - sin*py) / dist, (sin*px + cos*py) / dist
+			}
+		}
+	}
+
+	// mirrored points are told apart by their position
+	if len(corners) == 2 {
+		if areaX(corners[0], upright) > areaX(corners[1], upright) {
+			corners[0], corners[1] = corners[1], corners[0]
+		}
+		ret.MouthLeft, ret.MouthRight = corners[0], corners[1]
+	}
+	if len(lips) == 2 {
+		if areaY(lips[0], upright) > areaY(lips[1], upright) {
+			lips[0], lips[1] = lips[1], lips[0]
+		}
+		ret.UpperLip, ret.LowerLip = lips[0], lips[1]
+	}
+
+	if ret.LeftEye == nil || ret.RightEye == nil {
+		return ret
+	}
+	for _, l := range eyeRegion {
+		x, y := upright(l)
+		left := x < 0
+		eyebrow := y < -eyebrowDrop
+		var inner, outer **Area
+		switch {
+		case left && eyebrow:
+			inner, outer = &ret.LeftEyebrowInner, &ret.LeftEyebrowOuter
+		case left:
+			inner, outer = &ret.LeftEyeInner, &ret.LeftEyeOuter
+		case eyebrow:
+			inner, outer = &ret.RightEyebrowInner, &ret.RightEyebrowOuter
+		default:
+			inner, outer = &ret.RightEyeInner, &ret.RightEyeOuter
+		}
+		// the inner point is the closest one to the eyes midpoint
+		if *inner == nil || math.Abs(x) < math.Abs(areaX(*inner, upright)) {
+			*inner = l
+		}
+		if *outer == nil || math.Abs(x) > math.Abs(areaX(*outer, upright)) {
+			*outer = l
+		}
+	}
+	return ret
+}
+
+// areaX returns the upright horizontal position of a
+func areaX(a *Area, upright func(*Area) (float64, float64)) float64 {
+	x, _ := upright(a)
+	return x
+}
+
+// areaY returns the upright vertical position of a
+func areaY(a *Area, upright func(*Area) (float64, float64)) float64 {
+	_, y := upright(a)
+	return y
+}
+
+// isEyeCascade returns true if name is a point of a pigo eye cascade or its mirror
+func isEyeCascade(name string) bool {
+	name = strings.TrimSuffix(name, "_v")
+	for _, eye := range eyeCascades {
+		if name == eye {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLandmarks(t *testing.T) {
+	t.Run("pigo", func(t *testing.T) {
+		eyes := Areas{
+			NewArea("eye_l", 100, 80, 10),
+			NewArea("eye_r", 100, 120, 10),
+		}
+		landmarks := Areas{
+			NewArea("lp46", 100, 70, 10),
+			NewArea("lp44", 100, 90, 10),
+			NewArea("lp38", 80, 75, 10),
+			NewArea("lp312", 82, 95, 10),
+			NewArea("lp46_v", 100, 130, 10),
+			NewArea("lp44_v", 100, 110, 10),
+			NewArea("mouth_lp93", 125, 100, 10),
+			NewArea("mouth_lp84", 150, 115, 10),
+			NewArea("mouth_lp82", 158, 100, 10),
+			NewArea("mouth_lp81", 145, 100, 10),
+			NewArea("lp84", 150, 85, 10),
+		}
+		l := NewLandmarks(eyes, landmarks)
+		assert.Equal(t, &eyes[0], l.LeftEye)
+		assert.Equal(t, &eyes[1], l.RightEye)
+		assert.Equal(t, "lp46", l.LeftEyeOuter.Name)
+		assert.Equal(t, "lp44", l.LeftEyeInner.Name)
+		assert.Equal(t, "lp38", l.LeftEyebrowOuter.Name)
+		assert.Equal(t, "lp312", l.LeftEyebrowInner.Name)
+		assert.Equal(t, "lp46_v", l.RightEyeOuter.Name)
+		assert.Equal(t, "lp44_v", l.RightEyeInner.Name)
+		assert.Nil(t, l.RightEyebrowInner)
+		assert.Equal(t, "mouth_lp93", l.NoseTip.Name)
+		assert.Equal(t, "lp84", l.MouthLeft.Name)
+		assert.Equal(t, "mouth_lp84", l.MouthRight.Name)
+		assert.Equal(t, "mouth_lp81", l.UpperLip.Name)
+		assert.Equal(t, "mouth_lp82", l.LowerLip.Name)
+	})
+
+	t.Run("mtcnn", func(t *testing.T) {
+		l := NewLandmarks(
+			Areas{NewArea("eye_l", 100, 80, 10), NewArea("eye_r", 100, 120, 10)},
+			Areas{NewArea("nose", 125, 100, 10), NewArea("mouth_l", 150, 85, 10), NewArea("mouth_r", 150, 115, 10)},
+		)
+		assert.Equal(t, 125, l.NoseTip.Row)
+		assert.Equal(t, 85, l.MouthLeft.Col)
+		assert.Equal(t, 115, l.MouthRight.Col)
+		assert.Nil(t, l.LeftEyeInner)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		f := Face{Eyes: Areas{NewArea("eye_l", 100, 80, 10)}}
+		f.Points = NewLandmarks(f.Eyes, f.Landmarks)
+		b, err := json.Marshal(f)
+		if assert.NoError(t, err) {
+			assert.Contains(t, string(b), `"points":{"left_eye":{"name":"eye_l","x":100,"y":80,"size":10}}`)
+		}
+	})
+}
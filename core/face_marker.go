@@ -38,6 +38,14 @@ func (f FaceMarker) Face() Face {
 	return f.face
 }
 
+// Pose returns the head pose of the marker face, false if both eyes of the face were not found
+func (f FaceMarker) Pose() (Pose, bool) {
+	if !f.face.HasPose() {
+		return Pose{}, false
+	}
+	return f.face.Pose(), true
+}
+
 // SetError set face maker match failed
 func (f *FaceMarker) SetError(err error) {
 	f.err = err
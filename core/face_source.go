@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"image"
+)
+
+// Transform represents the scale of face coordinates relative to the source image,
+// faces are detected in a copy of the source image downscaled to MaxImageSize by default.
+type Transform struct {
+	// Scale source image pixels per face coordinate pixel, 0 means not scaled
+	Scale float64 `json:"scale,omitempty"`
+	// Rows source image height
+	Rows int `json:"rows,omitempty"`
+	// Cols source image width
+	Cols int `json:"cols,omitempty"`
+}
+
+// newTransform returns the transform from faces detected in img to the source image
+func newTransform(source image.Image, img image.Image) Transform {
+	sourceBounds, bounds := source.Bounds(), img.Bounds()
+	if bounds.Dx() == 0 {
+		return Transform{}
+	}
+	return Transform{
+		Scale: float64(sourceBounds.Dx()) / float64(bounds.Dx()),
+		Rows:  sourceBounds.Dy(),
+		Cols:  sourceBounds.Dx(),
+	}
+}
+
+// setTransform records the transform from faces detected in img to the source image
+func (faces Faces) setTransform(source image.Image, img image.Image) {
+	transform := newTransform(source, img)
+	for i := range faces {
+		faces[i].Transform = transform
+	}
+}
+
+// FaceSource represents face coordinates in source image pixels
+type FaceSource struct {
+	Rows      int       `json:"rows,omitempty"`
+	Cols      int       `json:"cols,omitempty"`
+	X         int       `json:"x"`
+	Y         int       `json:"y"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Area      Area      `json:"face"`
+	Eyes      Areas     `json:"eyes,omitempty"`
+	Landmarks Areas     `json:"landmarks,omitempty"`
+	Points    Landmarks `json:"points"`
+}
+
+// Source returns the face coordinates in source image pixels
+func (f *Face) Source() FaceSource {
+	src := f.sourceFace()
+	rect := src.rect()
+	return FaceSource{
+		Rows:      src.Rows,
+		Cols:      src.Cols,
+		X:         rect.Min.X,
+		Y:         rect.Min.Y,
+		Width:     rect.Dx(),
+		Height:    rect.Dy(),
+		Area:      src.Area,
+		Eyes:      src.Eyes,
+		Landmarks: src.Landmarks,
+		Points:    src.Points,
+	}
+}
+
+// Rect returns the face rectangle in source image pixels
+func (f *Face) Rect() image.Rectangle {
+	src := f.sourceFace()
+	return src.rect()
+}
+
+// MarshalJSON implements json.Marshaler interface, adds face coordinates in source image pixels
+// and the head pose if both eyes were found
+func (f Face) MarshalJSON() ([]byte, error) {
+	type face Face
+	ret := struct {
+		face
+		Source FaceSource `json:"source"`
+		Pose   *Pose      `json:"pose,omitempty"`
+	}{
+		face:   face(f),
+		Source: f.Source(),
+	}
+	if f.HasPose() {
+		pose := f.Pose()
+		ret.Pose = &pose
+	}
+	return json.Marshal(ret)
+}
+
+// sourceFace returns a copy of the face in source image coordinates
+func (f *Face) sourceFace() Face {
+	if f.Transform.Scale == 0 {
+		return *f
+	}
+	rows, cols := f.Transform.Rows, f.Transform.Cols
+	if rows == 0 || cols == 0 {
+		rows = int(float64(f.Rows) * f.Transform.Scale)
+		cols = int(float64(f.Cols) * f.Transform.Scale)
+	}
+	src := transformFace(*f, f.Transform.Scale, 0, 0, rows, cols)
+	src.Transform = Transform{}
+	return src
+}
+
+// rect returns the face rectangle in face coordinates, clipped to the image if its size is known
+func (f *Face) rect() image.Rectangle {
+	half := f.Area.Scale / 2
+	rect := image.Rect(f.Area.Col-half, f.Area.Row-half, f.Area.Col-half+f.Area.Scale, f.Area.Row-half+f.Area.Scale)
+	if f.Rows > 0 && f.Cols > 0 {
+		rect = rect.Intersect(image.Rect(0, 0, f.Cols, f.Rows))
+	}
+	return rect
+}
+
+// transformFace scales face coordinates then moves them by dRow and dCol into an image of rows and cols
+func transformFace(f Face, scale float64, dRow int, dCol int, rows int, cols int) Face {
+	point := func(a Area) Area {
+		return NewArea(
+			a.Name,
+			int(float64(a.Row)*scale)+dRow,
+			int(float64(a.Col)*scale)+dCol,
+			int(float64(a.Scale)*scale),
+		)
+	}
+	points := func(areas Areas) Areas {
+		if areas == nil {
+			return nil
+		}
+		ret := make(Areas, 0, len(areas))
+		for _, a := range areas {
+			ret = append(ret, point(a))
+		}
+		return ret
+	}
+	f.Rows = rows
+	f.Cols = cols
+	f.Area = point(f.Area)
+	f.Eyes = points(f.Eyes)
+	f.Landmarks = points(f.Landmarks)
+	f.Points = NewLandmarks(f.Eyes, f.Landmarks)
+	return f
+}
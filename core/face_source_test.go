@@ -0,0 +1,77 @@
+package core
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFace_Source(t *testing.T) {
+	f := Face{
+		Rows:      480,
+		Cols:      640,
+		Area:      NewArea("face", 100, 200, 50),
+		Eyes:      Areas{NewArea("eye_l", 90, 190, 6), NewArea("eye_r", 90, 210, 6)},
+		Landmarks: Areas{NewArea("nose", 105, 200, 6)},
+	}
+
+	t.Run("not scaled", func(t *testing.T) {
+		assert.Equal(t, image.Rect(175, 75, 225, 125), f.Rect())
+		assert.Equal(t, f.Area, f.Source().Area)
+	})
+
+	source := image.NewGray(image.Rect(0, 0, 2560, 1920))
+	img := image.NewGray(image.Rect(0, 0, 640, 480))
+	faces := Faces{f}
+	faces.setTransform(source, img)
+	f = faces[0]
+
+	t.Run("scaled", func(t *testing.T) {
+		assert.Equal(t, Transform{Scale: 4, Rows: 1920, Cols: 2560}, f.Transform)
+		assert.Equal(t, image.Rect(700, 300, 900, 500), f.Rect())
+
+		src := f.Source()
+		assert.Equal(t, 1920, src.Rows)
+		assert.Equal(t, 2560, src.Cols)
+		assert.Equal(t, NewArea("face", 400, 800, 200), src.Area)
+		assert.Equal(t, NewArea("eye_r", 360, 840, 24), src.Eyes[1])
+		assert.Equal(t, NewArea("nose", 420, 800, 24), *src.Points.NoseTip)
+		// the face itself is untouched
+		assert.Equal(t, 100, f.Area.Row)
+		assert.Equal(t, 90, f.Eyes[0].Row)
+	})
+
+	t.Run("clipped", func(t *testing.T) {
+		edge := f
+		edge.Area = NewArea("face", 10, 10, 50)
+		assert.Equal(t, image.Rect(0, 0, 140, 140), edge.Rect())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		b, err := json.Marshal(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result struct {
+			Area   Area       `json:"face"`
+			Source FaceSource `json:"source"`
+		}
+		if err := json.Unmarshal(b, &result); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, f.Area, result.Area)
+		assert.Equal(t, 700, result.Source.X)
+		assert.Equal(t, 300, result.Source.Y)
+		assert.Equal(t, 200, result.Source.Width)
+		assert.Equal(t, 200, result.Source.Height)
+		assert.Len(t, result.Source.Eyes, 2)
+
+		var decoded Face
+		if assert.NoError(t, json.Unmarshal(b, &decoded)) {
+			assert.Equal(t, f.Transform, decoded.Transform)
+			assert.Equal(t, f.Rect(), decoded.Rect())
+		}
+	})
+}
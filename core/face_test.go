@@ -1,6 +1,8 @@
 package core
 
 import (
+	"encoding/json"
+	"image"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -102,3 +104,127 @@ func TestFace_CropArea(t *testing.T) {
 		t.Logf("marker: %#v", f.CropArea())
 	})
 }
+
+func TestFace_EyesPoints(t *testing.T) {
+	t.Run("both eyes", func(t *testing.T) {
+		f := Face{
+			Eyes: Areas{
+				NewArea("eye_l", 100, 80, 10),
+				NewArea("eye_r", 110, 140, 10),
+			},
+		}
+		left, right, ok := f.EyesPoints()
+		assert.True(t, ok)
+		assert.Equal(t, image.Pt(80, 100), left)
+		assert.Equal(t, image.Pt(140, 110), right)
+	})
+	t.Run("one eye", func(t *testing.T) {
+		f := Face{
+			Eyes: Areas{
+				NewArea("eye_l", 100, 80, 10),
+			},
+		}
+		_, _, ok := f.EyesPoints()
+		assert.False(t, ok)
+	})
+}
+
+func TestFace_Pose(t *testing.T) {
+	// five point reference template scaled by 10
+	newFace := func() Face {
+		return Face{
+			Eyes: Areas{
+				NewArea("eye_l", 517, 383, 40),
+				NewArea("eye_r", 515, 735, 40),
+			},
+			Landmarks: Areas{
+				NewArea("nose", 717, 560, 40),
+				NewArea("mouth_l", 924, 415, 40),
+				NewArea("mouth_r", 922, 707, 40),
+			},
+		}
+	}
+
+	t.Run("frontal", func(t *testing.T) {
+		f := newFace()
+		pose := f.Pose()
+		assert.InDelta(t, 0, pose.Yaw, 2)
+		assert.InDelta(t, 0, pose.Pitch, 2)
+		assert.InDelta(t, 0, pose.Roll, 2)
+		assert.Equal(t, 1.0, pose.Confidence)
+	})
+
+	t.Run("turned", func(t *testing.T) {
+		f := newFace()
+		f.Landmarks[0].Col += 100
+		pose := f.Pose()
+		assert.Greater(t, pose.Yaw, 20.0)
+		assert.InDelta(t, 0, pose.Roll, 2)
+	})
+
+	t.Run("looking down", func(t *testing.T) {
+		f := newFace()
+		f.Landmarks[0].Row += 60
+		assert.Greater(t, f.Pose().Pitch, 10.0)
+	})
+
+	t.Run("tilted", func(t *testing.T) {
+		f := newFace()
+		f.Eyes[1].Row += 352
+		pose := f.Pose()
+		assert.InDelta(t, 45, pose.Roll, 1)
+	})
+
+	t.Run("eyes only", func(t *testing.T) {
+		f := newFace()
+		f.Landmarks = nil
+		pose := f.Pose()
+		assert.Equal(t, 0.3, pose.Confidence)
+		assert.Equal(t, 0.0, pose.Yaw)
+	})
+
+	t.Run("no eyes", func(t *testing.T) {
+		f := newFace()
+		f.Eyes = nil
+		assert.False(t, f.HasPose())
+		assert.Equal(t, Pose{}, f.Pose())
+	})
+
+	t.Run("marker", func(t *testing.T) {
+		f := newFace()
+		pose, ok := NewFaceMarker(f, "a", 0).Pose()
+		assert.True(t, ok)
+		assert.Equal(t, f.Pose(), pose)
+
+		f.Eyes = nil
+		_, ok = NewFaceMarker(f, "a", 0).Pose()
+		assert.False(t, ok)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		f := newFace()
+		buf, err := json.Marshal(f)
+		if !assert.NoError(t, err) {
+			return
+		}
+		var ret struct {
+			Eyes Areas `json:"eyes"`
+			Pose *Pose `json:"pose"`
+		}
+		assert.NoError(t, json.Unmarshal(buf, &ret))
+		assert.Equal(t, f.Eyes, ret.Eyes)
+		if assert.NotNil(t, ret.Pose) {
+			assert.Equal(t, f.Pose(), *ret.Pose)
+		}
+
+		var decoded Face
+		assert.NoError(t, json.Unmarshal(buf, &decoded))
+		assert.Equal(t, f.Landmarks, decoded.Landmarks)
+
+		f.Eyes = nil
+		buf, err = json.Marshal(&f)
+		if assert.NoError(t, err) {
+			assert.NotContains(t, string(buf), `"pose"`)
+		}
+	})
+}
@@ -1,25 +1,27 @@
 package core
 
 import (
-	"embed"
 	"errors"
-	"path/filepath"
+	"io/fs"
+	"path"
 
 	pigo "github.com/esimov/pigo/core"
 )
 
-//go:embed cascade/lps
-var efs embed.FS
-
 // FlpCascade holds the binary representation of the facial landmark points cascade files
 type FlpCascade struct {
 	*pigo.PuplocCascade
 	error
 }
 
-// ReadCascadeDir reads the facial landmark points cascade files from the provided directory.
+// ReadCascadeDir reads the facial landmark points cascade files from the provided embedded directory.
 func ReadCascadeDir(plc *pigo.PuplocCascade, path string) (result map[string][]*FlpCascade, err error) {
-	cascades, err := efs.ReadDir(path)
+	return ReadCascadeFS(plc, cascadeFS, path)
+}
+
+// ReadCascadeFS reads the facial landmark points cascade files from the provided directory of fsys.
+func ReadCascadeFS(plc *pigo.PuplocCascade, fsys fs.FS, dir string) (result map[string][]*FlpCascade, err error) {
+	cascades, err := fs.ReadDir(fsys, dir)
 
 	if len(cascades) == 0 {
 		return result, errors.New("the cascade directory is empty")
@@ -31,15 +33,15 @@ func ReadCascadeDir(plc *pigo.PuplocCascade, path string) (result map[string][]*
 
 	result = make(map[string][]*FlpCascade, len(cascades))
 	for _, cascade := range cascades {
-		cf := filepath.Join(path, cascade.Name())
+		cf := path.Join(dir, cascade.Name())
 
-		f, err := efs.ReadFile(cf)
+		f, err := fs.ReadFile(fsys, cf)
 
 		if err != nil {
 			return result, err
 		}
 
-		flpc, err := plc.UnpackCascade(f)
+		flpc, err := unpackPuploc(plc, cascade.Name(), f)
 
 		if err != nil {
 			return result, err
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestFilename represents the optional model manifest filename next to the model
+const ManifestFilename = "manifest.json"
+
+// Normalization represents model input normalization mode
+type Normalization string
+
+const (
+	// PrewhitenNormalization normalizes each image to zero mean and unit variance
+	PrewhitenNormalization Normalization = "prewhiten"
+	// ScaleNormalization scales pixel values to [-1, 1]
+	ScaleNormalization Normalization = "scale"
+)
+
+// Manifest describes tensor names, input size and embedding dimension of a model
+type Manifest struct {
+	// Tags saved model tags
+	Tags []string `json:"tags,omitempty"`
+	// Input input placeholder op name
+	Input string `json:"input,omitempty"`
+	// PhaseTrain phase train placeholder op name, empty if the model has no such placeholder
+	PhaseTrain string `json:"phase_train"`
+	// Output embeddings op name
+	Output string `json:"output,omitempty"`
+	// Width input image width
+	Width int `json:"width,omitempty"`
+	// Height input image height
+	Height int `json:"height,omitempty"`
+	// Normalization input normalization mode
+	Normalization Normalization `json:"normalization,omitempty"`
+	// Dimension embedding dimension
+	Dimension int `json:"dimension,omitempty"`
+}
+
+// DefaultManifest returns the manifest of the facenet 512-d saved model
+func DefaultManifest() Manifest {
+	return Manifest{
+		Tags:          []string{"serve"},
+		Input:         "input",
+		PhaseTrain:    "phase_train",
+		Output:        "embeddings",
+		Width:         CropSize.Width,
+		Height:        CropSize.Height,
+		Normalization: PrewhitenNormalization,
+		Dimension:     512,
+	}
+}
+
+// ManifestPath returns the manifest path of a model.
+// It is ManifestFilename inside a SavedModel directory, or the frozen graph path with a .json extension.
+func ManifestPath(modelPath string) string {
+	if IsFrozenGraph(modelPath) {
+		return strings.TrimSuffix(modelPath, FrozenGraphExt) + ".json"
+	}
+	return filepath.Join(modelPath, ManifestFilename)
+}
+
+// LoadManifest reads the manifest next to the model, omitted fields keep their default values
+func LoadManifest(modelPath string) (Manifest, error) {
+	manifest := DefaultManifest()
+	buf, err := os.ReadFile(ManifestPath(modelPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return manifest, err
+	}
+	if err := json.Unmarshal(buf, &manifest); err != nil {
+		return manifest, err
+	}
+	if manifest.Width <= 0 || manifest.Height <= 0 {
+		return manifest, NewError(ImageToTensorSizeErr, "manifest width and height must be > 0")
+	}
+	if manifest.Input == "" || manifest.Output == "" {
+		return manifest, NewError(InvalidManifestErr, "manifest input and output must not be empty")
+	}
+	switch manifest.Normalization {
+	case PrewhitenNormalization, ScaleNormalization:
+	default:
+		return manifest, NewError(InvalidManifestErr, fmt.Sprintf("unknown manifest normalization %q", manifest.Normalization))
+	}
+	return manifest, nil
+}
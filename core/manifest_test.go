@@ -0,0 +1,74 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadManifest(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		manifest, err := LoadManifest(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, DefaultManifest(), manifest)
+	})
+	t.Run("arcface", func(t *testing.T) {
+		dir := t.TempDir()
+		buf := []byte(`{"phase_train":"","output":"fc1","width":112,"height":112,"normalization":"scale","dimension":128}`)
+		if err := os.WriteFile(filepath.Join(dir, ManifestFilename), buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := LoadManifest(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"serve"}, manifest.Tags)
+		assert.Equal(t, "input", manifest.Input)
+		assert.Equal(t, "", manifest.PhaseTrain)
+		assert.Equal(t, "fc1", manifest.Output)
+		assert.Equal(t, 112, manifest.Width)
+		assert.Equal(t, ScaleNormalization, manifest.Normalization)
+		assert.Equal(t, 128, manifest.Dimension)
+	})
+	t.Run("invalid size", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ManifestFilename), []byte(`{"width":0}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadManifest(dir)
+		assert.Error(t, err)
+	})
+	for name, manifest := range map[string]string{
+		"empty input":           `{"input":""}`,
+		"empty output":          `{"output":""}`,
+		"unknown normalization": `{"normalization":"standard"}`,
+	} {
+		manifest := manifest
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, ManifestFilename), []byte(manifest), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadManifest(dir)
+			if assert.Error(t, err) {
+				assert.Equal(t, InvalidManifestErr, err.(Error).Code)
+			}
+		})
+	}
+}
+
+func TestManifestPath(t *testing.T) {
+	dir := t.TempDir()
+	frozenGraph := filepath.Join(dir, "20180402-114759.pb")
+	if err := os.WriteFile(frozenGraph, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, IsFrozenGraph(frozenGraph))
+	assert.False(t, IsFrozenGraph(dir))
+	assert.Equal(t, filepath.Join(dir, "20180402-114759.json"), ManifestPath(frozenGraph))
+	assert.Equal(t, filepath.Join(dir, ManifestFilename), ManifestPath(dir))
+}
@@ -0,0 +1,64 @@
+package core
+
+import (
+	"math"
+)
+
+// Distance returns the distance between two embeddings under the metric
+func (m Metric) Distance(face1 []float32, face2 []float32) (float64, error) {
+	switch m {
+	case Metric_SQUARED_EUCLIDEAN:
+		dist, err := EuclideanDistance(face1, face2)
+		return dist * dist, err
+	case Metric_COSINE:
+		return CosineDistance(face1, face2)
+	default:
+		return EuclideanDistance(face1, face2)
+	}
+}
+
+// FromEuclidean converts a euclidean distance threshold into the metric units.
+// The conversion is exact for L2 normalized embeddings, where cosine distance = euclidean distance² / 2.
+func (m Metric) FromEuclidean(dist float64) float64 {
+	switch m {
+	case Metric_SQUARED_EUCLIDEAN:
+		return dist * dist
+	case Metric_COSINE:
+		return dist * dist / 2
+	default:
+		return dist
+	}
+}
+
+// MatchDist returns the default match distance of the metric
+func (m Metric) MatchDist() float64 {
+	return m.FromEuclidean(MatchDist)
+}
+
+// collisionFloor returns the min collision radius to be considered
+func (m Metric) collisionFloor() float64 {
+	return m.FromEuclidean(0.1)
+}
+
+// radiusMargin returns the margin added to radius and removed from collision radius
+func (m Metric) radiusMargin() float64 {
+	return m.FromEuclidean(0.01)
+}
+
+// CosineDistance calculate cosine distance (1 - cosine similarity) between two vectors
+func CosineDistance(face1 []float32, face2 []float32) (float64, error) {
+	if err := checkDimension(face1, face2); err != nil {
+		return 0, err
+	}
+	var dot, norm1, norm2 float64
+	for k := range face1 {
+		dot += float64(face1[k]) * float64(face2[k])
+		norm1 += float64(face1[k]) * float64(face1[k])
+		norm2 += float64(face2[k]) * float64(face2[k])
+	}
+	if norm1 == 0 || norm2 == 0 {
+		return 1, nil
+	}
+	// Rounding errors may produce tiny negative distances for identical vectors.
+	return math.Max(0, 1-dot/math.Sqrt(norm1*norm2)), nil
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetric_Distance(t *testing.T) {
+	a := []float32{1, 0}
+	b := []float32{0, 1}
+	t.Run("euclidean", func(t *testing.T) {
+		dist, err := Metric_EUCLIDEAN.Distance(a, b)
+		assert.Nil(t, err)
+		assert.InDelta(t, 1.41421356, dist, 1e-6)
+	})
+	t.Run("squared euclidean", func(t *testing.T) {
+		dist, err := Metric_SQUARED_EUCLIDEAN.Distance(a, b)
+		assert.Nil(t, err)
+		assert.InDelta(t, 2, dist, 1e-6)
+	})
+	t.Run("cosine", func(t *testing.T) {
+		dist, err := Metric_COSINE.Distance(a, b)
+		assert.Nil(t, err)
+		assert.InDelta(t, 1, dist, 1e-6)
+		dist, err = Metric_COSINE.Distance(a, a)
+		assert.Nil(t, err)
+		assert.InDelta(t, 0, dist, 1e-6)
+	})
+	t.Run("dimension mismatch", func(t *testing.T) {
+		_, err := Metric_COSINE.Distance(a, []float32{1})
+		assert.Error(t, err)
+	})
+}
+
+func TestMetric_FromEuclidean(t *testing.T) {
+	// For unit vectors every metric must agree on the ordering of a threshold.
+	a := L2Normalize([]float32{1, 2, 3})
+	b := L2Normalize([]float32{1, 2, 4})
+	euclidean, _ := Metric_EUCLIDEAN.Distance(a, b)
+	for _, metric := range []Metric{Metric_SQUARED_EUCLIDEAN, Metric_COSINE} {
+		dist, _ := metric.Distance(a, b)
+		assert.InDelta(t, metric.FromEuclidean(euclidean), dist, 1e-6, metric.String())
+	}
+}
+
+func TestPeople_SetMetric(t *testing.T) {
+	people := testPeople()
+	people.SetMetric(Metric_COSINE)
+	assert.Equal(t, Metric_COSINE.MatchDist(), people.MatchThreshold())
+
+	person, _, err := people.Match([]float32{1, 1.05, 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "b", person.GetName())
+}
+
+func TestPeople_SetMetricRadii(t *testing.T) {
+	// persons of close embeddings collide with each other
+	newPeople := func() *People {
+		rnd := rand.New(rand.NewSource(1))
+		people := new(People)
+		for i := 0; i < 20; i++ {
+			people.List = append(people.List, randomPerson(rnd, fmt.Sprintf("person-%d", i), 4, 3))
+		}
+		return people
+	}
+	for _, metric := range []Metric{Metric_COSINE, Metric_SQUARED_EUCLIDEAN, Metric_EUCLIDEAN} {
+		t.Run(metric.String(), func(t *testing.T) {
+			people := newPeople()
+			people.Setup()
+			people.SetMetric(metric)
+
+			expected := newPeople()
+			expected.Metric = metric
+			expected.Setup()
+			var collisions int
+			for i, person := range people.GetList() {
+				assert.Equal(t, expected.List[i].GetRadius(), person.GetRadius(), person.GetName())
+				assert.Equal(t, expected.List[i].GetCollisionRadius(), person.GetCollisionRadius(), person.GetName())
+				if person.GetCollisionRadius() > 0 {
+					collisions++
+				}
+			}
+			assert.Greater(t, collisions, 0)
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"sort"
+
+	"github.com/disintegration/imaging"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+// MTCNN operation names of the frozen graph made by scripts/converter.py
+const (
+	mtcnnPNetInput  = "pnet/input"
+	mtcnnPNetReg    = "pnet/conv4-2/BiasAdd"
+	mtcnnPNetProb   = "pnet/prob1"
+	mtcnnRNetInput  = "rnet/input"
+	mtcnnRNetReg    = "rnet/conv5-2/conv5-2"
+	mtcnnRNetProb   = "rnet/prob1"
+	mtcnnONetInput  = "onet/input"
+	mtcnnONetReg    = "onet/conv6-2/conv6-2"
+	mtcnnONetPoints = "onet/conv6-3/conv6-3"
+	mtcnnONetProb   = "onet/prob1"
+)
+
+// MTCNN is a face Detector running the frozen MTCNN pnet, rnet and onet graph made by scripts/converter.py.
+// Faces are returned with five landmarks: both eyes, the nose and both mouth corners.
+type MTCNN struct {
+	tfSession
+	modelPath   string
+	thresholds  [3]float32
+	scaleFactor float64
+}
+
+// NewMTCNN returns a new MTCNN face detector, the frozen graph is loaded on first use
+func NewMTCNN(modelPath string, opts ...MTCNNOption) *MTCNN {
+	m := &MTCNN{
+		modelPath:   modelPath,
+		thresholds:  [3]float32{0.6, 0.7, 0.7},
+		scaleFactor: 0.709,
+	}
+	for _, opt := range opts {
+		opt.apply(m)
+	}
+	return m
+}
+
+// LoadModel loads the MTCNN frozen graph if not loaded yet.
+func (m *MTCNN) LoadModel() error {
+	return m.load(m.openModel,
+		mtcnnPNetInput, mtcnnPNetReg, mtcnnPNetProb,
+		mtcnnRNetInput, mtcnnRNetReg, mtcnnRNetProb,
+		mtcnnONetInput, mtcnnONetReg, mtcnnONetPoints, mtcnnONetProb,
+	)
+}
+
+// openModel opens the MTCNN frozen graph
+func (m *MTCNN) openModel() (*tf.Graph, *tf.Session, error) {
+	return loadFrozenGraph(m.modelPath)
+}
+
+// mtcnnBox represents a face candidate, coordinates are 1-based and inclusive as in the reference implementation
+type mtcnnBox struct {
+	x1, y1, x2, y2 float64
+	score          float32
+	reg            [4]float32
+	points         [10]float32
+}
+
+// Detect implements Detector interface
+func (m *MTCNN) Detect(ctx context.Context, img image.Image, findLandmarks bool, minSize int) (Faces, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := m.acquire(m.LoadModel); err != nil {
+		return nil, err
+	}
+	defer m.release()
+
+	if minSize < 12 {
+		minSize = 12
+	}
+	src := imaging.Clone(img)
+
+	boxes, err := m.proposals(ctx, src, minSize)
+	if err != nil || len(boxes) == 0 {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	boxes, err = m.refine(src, boxes)
+	if err != nil || len(boxes) == 0 {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	boxes, err = m.output(src, boxes)
+	if err != nil {
+		return nil, err
+	}
+	return mtcnnFaces(boxes, src.Bounds(), findLandmarks), nil
+}
+
+// proposals runs pnet over the image pyramid
+func (m *MTCNN) proposals(ctx context.Context, src *image.NRGBA, minSize int) ([]mtcnnBox, error) {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	scale := 12 / float64(minSize)
+	minl := math.Min(float64(w), float64(h)) * scale
+
+	factor := m.scaleFactor
+	if factor <= 0 || factor >= 1 {
+		factor = 0.709
+	}
+
+	var boxes []mtcnnBox
+	for ; minl >= 12; scale, minl = scale*factor, minl*factor {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ws, hs := int(math.Ceil(float64(w)*scale)), int(math.Ceil(float64(h)*scale))
+		out, err := m.run(mtcnnPNetInput, []*image.NRGBA{imaging.Resize(src, ws, hs, imaging.Box)}, mtcnnPNetReg, mtcnnPNetProb)
+		if err != nil {
+			return nil, err
+		}
+		reg, ok1 := out[0].Value().([][][][]float32)
+		prob, ok2 := out[1].Value().([][][][]float32)
+		if !ok1 || !ok2 || len(reg) != 1 || len(prob) != 1 {
+			return nil, NewError(InferenceFailedErr, "inference failed, unexpected pnet output shape")
+		}
+		// Outputs are indexed by x first, as inputs are transposed.
+		var candidates []mtcnnBox
+		for i := range prob[0] {
+			for j := range prob[0][i] {
+				score := prob[0][i][j][1]
+				if score < m.thresholds[0] {
+					continue
+				}
+				r := reg[0][i][j]
+				candidates = append(candidates, mtcnnBox{
+					x1:    math.Trunc(float64(2*i+1) / scale),
+					y1:    math.Trunc(float64(2*j+1) / scale),
+					x2:    math.Trunc(float64(2*i+12) / scale),
+					y2:    math.Trunc(float64(2*j+12) / scale),
+					score: score,
+					reg:   [4]float32{r[0], r[1], r[2], r[3]},
+				})
+			}
+		}
+		boxes = append(boxes, mtcnnNMS(candidates, 0.5, false)...)
+	}
+
+	boxes = mtcnnNMS(boxes, 0.7, false)
+	for i := range boxes {
+		b := &boxes[i]
+		w, h := b.x2-b.x1, b.y2-b.y1
+		b.x1 += float64(b.reg[0]) * w
+		b.y1 += float64(b.reg[1]) * h
+		b.x2 += float64(b.reg[2]) * w
+		b.y2 += float64(b.reg[3]) * h
+		b.square()
+		b.fix()
+	}
+	return boxes, nil
+}
+
+// refine runs rnet over the candidates
+func (m *MTCNN) refine(src *image.NRGBA, boxes []mtcnnBox) ([]mtcnnBox, error) {
+	boxes, crops := mtcnnCrops(src, boxes, 24)
+	if len(crops) == 0 {
+		return nil, nil
+	}
+	out, err := m.run(mtcnnRNetInput, crops, mtcnnRNetReg, mtcnnRNetProb)
+	if err != nil {
+		return nil, err
+	}
+	reg, ok1 := out[0].Value().([][]float32)
+	prob, ok2 := out[1].Value().([][]float32)
+	if !ok1 || !ok2 || len(reg) != len(boxes) || len(prob) != len(boxes) {
+		return nil, NewError(InferenceFailedErr, "inference failed, unexpected rnet output shape")
+	}
+	passed := make([]mtcnnBox, 0, len(boxes))
+	for k, b := range boxes {
+		if prob[k][1] <= m.thresholds[1] {
+			continue
+		}
+		b.score = prob[k][1]
+		b.reg = [4]float32{reg[k][0], reg[k][1], reg[k][2], reg[k][3]}
+		passed = append(passed, b)
+	}
+	passed = mtcnnNMS(passed, 0.7, false)
+	for i := range passed {
+		passed[i].regress()
+		passed[i].square()
+	}
+	return passed, nil
+}
+
+// output runs onet over the refined candidates and locates landmarks
+func (m *MTCNN) output(src *image.NRGBA, boxes []mtcnnBox) ([]mtcnnBox, error) {
+	for i := range boxes {
+		boxes[i].fix()
+	}
+	boxes, crops := mtcnnCrops(src, boxes, 48)
+	if len(crops) == 0 {
+		return nil, nil
+	}
+	out, err := m.run(mtcnnONetInput, crops, mtcnnONetReg, mtcnnONetPoints, mtcnnONetProb)
+	if err != nil {
+		return nil, err
+	}
+	reg, ok1 := out[0].Value().([][]float32)
+	points, ok2 := out[1].Value().([][]float32)
+	prob, ok3 := out[2].Value().([][]float32)
+	if !ok1 || !ok2 || !ok3 || len(reg) != len(boxes) || len(points) != len(boxes) || len(prob) != len(boxes) {
+		return nil, NewError(InferenceFailedErr, "inference failed, unexpected onet output shape")
+	}
+	passed := make([]mtcnnBox, 0, len(boxes))
+	for k, b := range boxes {
+		if prob[k][1] <= m.thresholds[2] {
+			continue
+		}
+		b.score = prob[k][1]
+		b.reg = [4]float32{reg[k][0], reg[k][1], reg[k][2], reg[k][3]}
+		w, h := b.x2-b.x1+1, b.y2-b.y1+1
+		for p := 0; p < 5; p++ {
+			b.points[p] = float32(w*float64(points[k][p]) + b.x1 - 1)
+			b.points[p+5] = float32(h*float64(points[k][p+5]) + b.y1 - 1)
+		}
+		b.regress()
+		passed = append(passed, b)
+	}
+	return mtcnnNMS(passed, 0.7, true), nil
+}
+
+// run feeds images into input and fetches outputs
+func (m *MTCNN) run(input string, imgs []*image.NRGBA, outputs ...string) ([]*tf.Tensor, error) {
+	tensor, err := mtcnnTensor(imgs)
+	if err != nil {
+		return nil, err
+	}
+	fetches := make([]tf.Output, 0, len(outputs))
+	for _, name := range outputs {
+		fetches = append(fetches, m.graph.Operation(name).Output(0))
+	}
+	out, err := m.session.Run(map[tf.Output]*tf.Tensor{
+		m.graph.Operation(input).Output(0): tensor,
+	}, fetches, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != len(outputs) {
+		return nil, NewError(InferenceFailedErr, fmt.Sprintf("inference failed, expect %d outputs, got %d", len(outputs), len(out)))
+	}
+	return out, nil
+}
+
+// mtcnnTensor stacks images into one [N,width,height,3] tensor scaled to [-1, 1].
+// Images are transposed because the MTCNN weights were converted from column major Caffe models.
+func mtcnnTensor(imgs []*image.NRGBA) (*tf.Tensor, error) {
+	batch := make([][][][]float32, 0, len(imgs))
+	for _, img := range imgs {
+		w, h := img.Bounds().Dx(), img.Bounds().Dy()
+		values := make([]float32, w*h*3)
+		cols := make([][]float32, w*h)
+		pixels := make([][][]float32, w)
+		for x := 0; x < w; x++ {
+			pixels[x] = cols[x*h : (x+1)*h]
+			for y := 0; y < h; y++ {
+				pix := img.Pix[y*img.Stride+x*4 : y*img.Stride+x*4+3]
+				v := values[(x*h+y)*3 : (x*h+y)*3+3]
+				for c := range v {
+					v[c] = (float32(pix[c]) - 127.5) * 0.0078125
+				}
+				pixels[x][y] = v
+			}
+		}
+		batch = append(batch, pixels)
+	}
+	return tf.NewTensor(batch)
+}
+
+// mtcnnCrops crops and resizes boxes from src, zero padded outside of the image, empty boxes are dropped
+func mtcnnCrops(src *image.NRGBA, boxes []mtcnnBox, size int) ([]mtcnnBox, []*image.NRGBA) {
+	kept := make([]mtcnnBox, 0, len(boxes))
+	crops := make([]*image.NRGBA, 0, len(boxes))
+	for _, b := range boxes {
+		w, h := int(b.x2-b.x1)+1, int(b.y2-b.y1)+1
+		if w <= 0 || h <= 0 {
+			continue
+		}
+		dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), src, image.Pt(int(b.x1)-1, int(b.y1)-1), draw.Src)
+		kept = append(kept, b)
+		crops = append(crops, imaging.Resize(dst, size, size, imaging.Linear))
+	}
+	return kept, crops
+}
+
+// square turns the box into a square around its center
+func (b *mtcnnBox) square() {
+	w, h := b.x2-b.x1, b.y2-b.y1
+	l := math.Max(w, h)
+	b.x1 += w*0.5 - l*0.5
+	b.y1 += h*0.5 - l*0.5
+	b.x2 = b.x1 + l
+	b.y2 = b.y1 + l
+}
+
+// regress calibrates the box with its regression offsets
+func (b *mtcnnBox) regress() {
+	w, h := b.x2-b.x1+1, b.y2-b.y1+1
+	b.x1 += float64(b.reg[0]) * w
+	b.y1 += float64(b.reg[1]) * h
+	b.x2 += float64(b.reg[2]) * w
+	b.y2 += float64(b.reg[3]) * h
+}
+
+// fix truncates the box coordinates
+func (b *mtcnnBox) fix() {
+	b.x1, b.y1 = math.Trunc(b.x1), math.Trunc(b.y1)
+	b.x2, b.y2 = math.Trunc(b.x2), math.Trunc(b.y2)
+}
+
+// mtcnnOverlap returns the intersection over union, or over the min area if useMin
+func mtcnnOverlap(a, b mtcnnBox, useMin bool) float64 {
+	areaA := (a.x2 - a.x1 + 1) * (a.y2 - a.y1 + 1)
+	areaB := (b.x2 - b.x1 + 1) * (b.y2 - b.y1 + 1)
+	w := math.Max(0, math.Min(a.x2, b.x2)-math.Max(a.x1, b.x1)+1)
+	h := math.Max(0, math.Min(a.y2, b.y2)-math.Max(a.y1, b.y1)+1)
+	inter := w * h
+	if useMin {
+		return inter / math.Min(areaA, areaB)
+	}
+	return inter / (areaA + areaB - inter)
+}
+
+// mtcnnNMS keeps the best scored boxes, suppressing the ones overlapping more than threshold
+func mtcnnNMS(boxes []mtcnnBox, threshold float64, useMin bool) []mtcnnBox {
+	sort.SliceStable(boxes, func(i, j int) bool {
+		return boxes[i].score > boxes[j].score
+	})
+	suppressed := make([]bool, len(boxes))
+	picked := make([]mtcnnBox, 0, len(boxes))
+	for i := range boxes {
+		if suppressed[i] {
+			continue
+		}
+		picked = append(picked, boxes[i])
+		for j := i + 1; j < len(boxes); j++ {
+			if !suppressed[j] && mtcnnOverlap(boxes[i], boxes[j], useMin) > threshold {
+				suppressed[j] = true
+			}
+		}
+	}
+	return picked
+}
+
+// mtcnnFaces converts boxes into faces sorted by size, scores are face probabilities in percent
+func mtcnnFaces(boxes []mtcnnBox, bounds image.Rectangle, findLandmarks bool) Faces {
+	sort.SliceStable(boxes, func(i, j int) bool {
+		return boxes[i].x2-boxes[i].x1 > boxes[j].x2-boxes[j].x1
+	})
+	faces := NewFaces(len(boxes))
+	for _, b := range boxes {
+		scale := int(math.Max(b.x2-b.x1, b.y2-b.y1))
+		f := Face{
+			Rows:  bounds.Dy(),
+			Cols:  bounds.Dx(),
+			Score: int(b.score * 100),
+			Area:  NewArea("face", int((b.y1+b.y2)/2), int((b.x1+b.x2)/2), scale),
+		}
+		if findLandmarks {
+			point := func(name string, k int) Area {
+				return NewArea(name, int(b.points[k+5]), int(b.points[k]), scale/4)
+			}
+			f.Eyes = Areas{point("eye_l", 0), point("eye_r", 1)}
+			f.Landmarks = Areas{point("nose", 2), point("mouth_l", 3), point("mouth_r", 4)}
+			f.Points = NewLandmarks(f.Eyes, f.Landmarks)
+		}
+		faces.Append(f)
+	}
+	return faces
+}
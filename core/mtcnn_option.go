@@ -0,0 +1,27 @@
+package core
+
+// MTCNNOption represents MTCNN option interface
+type MTCNNOption interface {
+	apply(*MTCNN)
+}
+
+type mtcnnOptionFunc func(*MTCNN)
+
+func (fn mtcnnOptionFunc) apply(m *MTCNN) {
+	fn(m)
+}
+
+// WithMTCNNThresholds set the face probability thresholds of pnet, rnet and onet, default 0.6, 0.7 and 0.7
+func WithMTCNNThresholds(pnet, rnet, onet float32) MTCNNOption {
+	return mtcnnOptionFunc(func(m *MTCNN) {
+		m.thresholds = [3]float32{pnet, rnet, onet}
+	})
+}
+
+// WithMTCNNScaleFactor set the image pyramid scale factor in (0, 1), default 0.709.
+// A smaller factor is faster but may miss faces.
+func WithMTCNNScaleFactor(scaleFactor float64) MTCNNOption {
+	return mtcnnOptionFunc(func(m *MTCNN) {
+		m.scaleFactor = scaleFactor
+	})
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMTCNNNMS(t *testing.T) {
+	boxes := []mtcnnBox{
+		{x1: 10, y1: 10, x2: 50, y2: 50, score: 0.8},
+		{x1: 12, y1: 12, x2: 52, y2: 52, score: 0.9},
+		{x1: 100, y1: 100, x2: 140, y2: 140, score: 0.7},
+		{x1: 20, y1: 20, x2: 40, y2: 40, score: 0.75},
+	}
+
+	t.Run("union", func(t *testing.T) {
+		picked := mtcnnNMS(append([]mtcnnBox(nil), boxes...), 0.5, false)
+		if assert.Len(t, picked, 3) {
+			assert.Equal(t, float32(0.9), picked[0].score)
+			assert.Equal(t, float32(0.75), picked[1].score)
+			assert.Equal(t, float32(0.7), picked[2].score)
+		}
+	})
+
+	t.Run("min", func(t *testing.T) {
+		// the small box lies inside the best one
+		picked := mtcnnNMS(append([]mtcnnBox(nil), boxes...), 0.5, true)
+		if assert.Len(t, picked, 2) {
+			assert.Equal(t, float32(0.9), picked[0].score)
+			assert.Equal(t, float32(0.7), picked[1].score)
+		}
+	})
+}
+
+func TestMTCNNBox(t *testing.T) {
+	b := mtcnnBox{x1: 10, y1: 20, x2: 30, y2: 60}
+	b.square()
+	assert.Equal(t, mtcnnBox{x1: 0, y1: 20, x2: 40, y2: 60}, b)
+
+	b = mtcnnBox{x1: 0, y1: 0, x2: 9, y2: 9, reg: [4]float32{0.1, -0.1, 0.2, 0}}
+	b.regress()
+	assert.InDelta(t, 1, b.x1, 1e-6)
+	assert.InDelta(t, -1, b.y1, 1e-6)
+	assert.InDelta(t, 11, b.x2, 1e-6)
+	assert.InDelta(t, 9, b.y2, 1e-6)
+}
+
+func TestMTCNNFaces(t *testing.T) {
+	boxes := []mtcnnBox{
+		{x1: 10, y1: 10, x2: 30, y2: 30, score: 0.95},
+		{x1: 100, y1: 50, x2: 180, y2: 130, score: 0.99, points: [10]float32{120, 160, 140, 125, 155, 80, 80, 95, 110, 110}},
+	}
+	faces := mtcnnFaces(boxes, image.Rect(0, 0, 320, 240), true)
+	if !assert.Len(t, faces, 2) {
+		return
+	}
+	f := faces[0]
+	assert.Equal(t, NewArea("face", 90, 140, 80), f.Area)
+	assert.Equal(t, 99, f.Score)
+	assert.Equal(t, 240, f.Rows)
+	assert.Equal(t, 320, f.Cols)
+	left, right, ok := f.EyesPoints()
+	assert.True(t, ok)
+	assert.Equal(t, image.Pt(120, 80), left)
+	assert.Equal(t, image.Pt(160, 80), right)
+	assert.Len(t, f.Landmarks, 3)
+
+	faces = mtcnnFaces(boxes, image.Rect(0, 0, 320, 240), false)
+	assert.Empty(t, faces[0].Eyes)
+	assert.Empty(t, faces[0].Landmarks)
+}
@@ -1,322 +1,257 @@
 package core
 
 import (
-	"bytes"
-	"errors"
+	"context"
+	"fmt"
 	"image"
-	"image/jpeg"
-	"math"
+	"os"
 	"path"
-	"sync"
+	"path/filepath"
 
-	"github.com/bububa/facenet/imageutil"
+	"github.com/disintegration/imaging"
 
 	tf "github.com/tensorflow/tensorflow/tensorflow/go"
-	"github.com/tensorflow/tensorflow/tensorflow/go/op"
 )
 
-// Net is a wrapper for the TensorFlow Facenet model.
+// FrozenGraphExt represents the file extension of frozen GraphDef models
+const FrozenGraphExt = ".pb"
+
+// Net is a wrapper for the TensorFlow Facenet model, either a SavedModel directory or a frozen GraphDef file.
 type Net struct {
-	model     *tf.SavedModel
-	modelPath string
-	modelName string
-	modelTags []string
-	mutex     sync.Mutex
+	tfSession
+	modelPath   string
+	modelName   string
+	manifest    Manifest
+	manifestErr error
+	l2Normalize bool
+	flip        bool
 }
 
 // NewNet returns a new TensorFlow Facenet instance.
-func NewNet(modelPath string) *Net {
-	return &Net{modelPath: modelPath, modelTags: []string{"serve"}}
+// The optional manifest next to the model is read here, a broken manifest is reported by LoadModel.
+func NewNet(modelPath string, opts ...NetOption) *Net {
+	manifest, err := LoadManifest(modelPath)
+	t := &Net{modelPath: modelPath, manifest: manifest, manifestErr: err}
+	for _, opt := range opts {
+		opt.apply(t)
+	}
+	return t
+}
+
+// Manifest returns the model manifest
+func (t *Net) Manifest() Manifest {
+	return t.manifest
 }
 
 // DetectMultiple detect multiple faces try to use different minSize
-func (t *Net) DetectMultiple(img image.Image, minSize int) (faces Faces, err error) {
-	src := imageutil.NormalizeImage(img, MaxImageSize)
-	faces, err = TrySizeExtractMultiple(src, false, minSize)
+func (t *Net) DetectMultiple(img image.Image, minSize int, opts ...DetectOption) (faces Faces, err error) {
+	return DetectMultiple(t, img, minSize, opts...)
+}
 
-	if err != nil {
-		return faces, err
-	}
+// DetectSingle detect single face try to use different minSize
+func (t *Net) DetectSingle(img image.Image, minSize int, opts ...DetectOption) (face Face, err error) {
+	return DetectSingle(t, img, minSize, opts...)
+}
 
-	if err = t.LoadModel(); err != nil {
-		return faces, err
-	}
-	for i, face := range faces {
-		thumb := imageutil.Thumb(src, face.CropArea(), CropSize)
-		if embeddings, err := t.getEmbeddings(thumb); err == nil {
-			faces[i].Embeddings = embeddings
-		}
-	}
-	return faces, nil
+// Detect runs the detection and facenet algorithms over the provided source image.
+func (t *Net) Detect(img image.Image, minSize int, expected int, opts ...DetectOption) (faces Faces, err error) {
+	return Detect(t, img, minSize, expected, opts...)
 }
 
-// DetectSingle detect single face try to use different minSize
-func (t *Net) DetectSingle(img image.Image, minSize int) (face Face, err error) {
-	src := imageutil.NormalizeImage(img, MaxImageSize)
-	face, err = TrySizeExtractSingle(src, false, minSize)
+// Train train images with label defined
+func (t *Net) Train(label string, images []image.Image, minSize int, opts ...DetectOption) (person Person, err error) {
+	return t.TrainContext(context.Background(), label, images, minSize, opts...)
+}
 
-	if err != nil {
-		return face, err
-	}
+// DetectMultipleContext detect multiple faces try to use different minSize, returns ctx.Err() once ctx is done
+func (t *Net) DetectMultipleContext(ctx context.Context, img image.Image, minSize int, opts ...DetectOption) (faces Faces, err error) {
+	return DetectMultipleContext(ctx, t, img, minSize, opts...)
+}
 
-	if err = t.LoadModel(); err != nil {
-		return face, err
-	}
-	thumb := imageutil.Thumb(src, face.CropArea(), CropSize)
-	if embeddings, err := t.getEmbeddings(thumb); err == nil {
-		face.Embeddings = embeddings
-	}
-	return face, nil
+// DetectSingleContext detect single face try to use different minSize, returns ctx.Err() once ctx is done
+func (t *Net) DetectSingleContext(ctx context.Context, img image.Image, minSize int, opts ...DetectOption) (face Face, err error) {
+	return DetectSingleContext(ctx, t, img, minSize, opts...)
 }
 
-// Detect runs the detection and facenet algorithms over the provided source image.
-func (t *Net) Detect(img image.Image, minSize int, expected int) (faces Faces, err error) {
-	src := imageutil.NormalizeImage(img, MaxImageSize)
-	faces, err = Extract(src, false, minSize)
+// DetectContext runs the detection and facenet algorithms over the provided source image, returns ctx.Err() once ctx is done
+func (t *Net) DetectContext(ctx context.Context, img image.Image, minSize int, expected int, opts ...DetectOption) (faces Faces, err error) {
+	return DetectContext(ctx, t, img, minSize, expected, opts...)
+}
 
+// TrainContext train images with label defined, returns ctx.Err() once ctx is done
+func (t *Net) TrainContext(ctx context.Context, label string, images []image.Image, minSize int, opts ...DetectOption) (person Person, err error) {
+	trained, err := TrainContext(ctx, t, label, images, minSize, opts...)
+	person.Name = trained.GetName()
+	person.Embeddings = trained.GetEmbeddings()
+	return
+}
+
+// Embed implement Embedder interface
+func (t *Net) Embed(img image.Image) ([]float32, error) {
+	embeddings, err := t.EmbedBatch([]image.Image{img})
 	if err != nil {
-		return faces, err
+		return nil, err
 	}
+	return embeddings[0], nil
+}
 
-	if c := len(faces); c == 0 || expected > 0 && c == expected {
-		return faces, nil
+// EmbedBatch implement BatchEmbedder interface, embeds all face crops in one session run
+func (t *Net) EmbedBatch(imgs []image.Image) ([][]float32, error) {
+	if len(imgs) == 0 {
+		return nil, nil
 	}
-
-	if err = t.LoadModel(); err != nil {
-		return faces, err
+	if err := t.acquire(t.LoadModel); err != nil {
+		return nil, err
 	}
-
-	for i, f := range faces {
-		if f.Area.Col == 0 || f.Area.Row == 0 {
-			continue
+	defer t.release()
+	inputs := imgs
+	if t.flip {
+		// Mirrored crops are appended to the same batch.
+		inputs = make([]image.Image, 0, len(imgs)*2)
+		inputs = append(inputs, imgs...)
+		for _, img := range imgs {
+			inputs = append(inputs, imaging.FlipH(img))
 		}
-
-		thumb := imageutil.Thumb(src, f.CropArea(), CropSize)
-		if embeddings, err := t.getEmbeddings(thumb); err == nil {
-			faces[i].Embeddings = embeddings
+	}
+	embeddings, err := t.getEmbeddings(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(inputs) {
+		return nil, NewError(InferenceFailedErr, fmt.Sprintf("inference failed, expect %d embeddings, got %d", len(inputs), len(embeddings)))
+	}
+	if t.flip {
+		embeddings = averageFlipped(embeddings)
+	}
+	if t.l2Normalize {
+		for i, embedding := range embeddings {
+			embeddings[i] = L2Normalize(embedding)
 		}
 	}
-
-	return faces, nil
+	return embeddings, nil
 }
 
-// Train train images with label defined
-func (t *Net) Train(label string, images []image.Image, minSize int) (person Person, err error) {
-	person.Name = label
-	person.Embeddings = make([]*Person_Embedding, 0, len(images))
-	for _, img := range images {
-		face, err := t.DetectSingle(img, minSize)
-		if err != nil {
-			return person, err
+// averageFlipped averages the embeddings of the first half with their mirrored ones in the second half, then renormalizes
+func averageFlipped(embeddings [][]float32) [][]float32 {
+	l := len(embeddings) / 2
+	ret := make([][]float32, l)
+	for i := 0; i < l; i++ {
+		avg := make([]float32, len(embeddings[i]))
+		for k, v := range embeddings[i] {
+			avg[k] = (v + embeddings[l+i][k]) / 2
 		}
-		person.Embeddings = append(person.Embeddings, &Person_Embedding{
-			Value: face.Embeddings[0],
-		})
+		ret[i] = L2Normalize(avg)
 	}
-	return
-}
-
-// ModelLoaded tests if the TensorFlow model is loaded.
-func (t *Net) ModelLoaded() bool {
-	return t.model != nil
+	return ret
 }
 
+// LoadModel loads the TensorFlow model if not loaded yet.
 func (t *Net) LoadModel() error {
-	t.mutex.Lock()
-	defer t.mutex.Unlock()
+	return t.load(t.openModel, t.manifest.Input, t.manifest.PhaseTrain, t.manifest.Output)
+}
 
-	if t.ModelLoaded() {
-		return nil
+// openModel opens the SavedModel directory or frozen GraphDef file of the model
+func (t *Net) openModel() (*tf.Graph, *tf.Session, error) {
+	if t.manifestErr != nil {
+		return nil, nil, t.manifestErr
 	}
 
 	modelPath := path.Join(t.modelPath)
 
 	// log.Printf("faces: loading %s\n", filepath.Base(modelPath))
 
-	// Load model
-	model, err := tf.LoadSavedModel(modelPath, t.modelTags, nil)
-
-	if err != nil {
-		return err
+	if IsFrozenGraph(modelPath) {
+		return loadFrozenGraph(modelPath)
 	}
-
-	t.model = model
-
-	return nil
+	return loadSavedModel(modelPath, t.manifest.Tags)
 }
 
-func (t *Net) getEmbeddings(img image.Image) ([][]float32, error) {
-	tensor, err := makeTensorFromImage(img, CropSize.Width, CropSize.Height)
-	//tensor, err := imageToTensor(img, CropSize.Width, CropSize.Height)
-
-	if err != nil {
-		// log.Printf("faces: failed to convert image to tensor: %v\n", err)
-		return nil, err
-	}
-
-	trainPhaseBoolTensor, err := tf.NewTensor(false)
-	if err != nil {
-		return nil, err
+// Warmup loads the model and runs a dummy inference, so that the first real inference is not slowed down by TensorFlow lazy initialization.
+func (t *Net) Warmup() error {
+	if t.manifestErr != nil {
+		return t.manifestErr
 	}
-
-	output, err := t.model.Session.Run(
-		map[tf.Output]*tf.Tensor{
-			t.model.Graph.Operation("input").Output(0):       tensor,
-			t.model.Graph.Operation("phase_train").Output(0): trainPhaseBoolTensor,
-		},
-		[]tf.Output{
-			t.model.Graph.Operation("embeddings").Output(0),
-		},
-		nil)
-
-	if err != nil {
-		// log.Printf("faces: %s\n", err)
-		return nil, err
-	}
-
-	if len(output) < 1 {
-		return nil, NewError(InferenceFailedErr, "inference failed, no output")
-	}
-	return output[0].Value().([][]float32), nil
+	img := image.NewRGBA(image.Rect(0, 0, t.manifest.Width, t.manifest.Height))
+	_, err := t.Embed(img)
+	return err
 }
 
-/*
-func imageToTensor(img image.Image, imageHeight, imageWidth int) (tfTensor *tf.Tensor, err error) {
-	if imageHeight <= 0 || imageWidth <= 0 {
-		return tfTensor, NewError(ImageToTensorSizeErr, "image width and height must be > 0")
-	}
-	var tfImage [1][][][3]float32
-
-	for j := 0; j < imageHeight; j++ {
-		tfImage[0] = append(tfImage[0], make([][3]float32, imageWidth))
+// IsFrozenGraph tests if modelPath is a frozen GraphDef file instead of a SavedModel directory
+func IsFrozenGraph(modelPath string) bool {
+	if filepath.Ext(modelPath) != FrozenGraphExt {
+		return false
 	}
+	info, err := os.Stat(modelPath)
+	return err == nil && info.Mode().IsRegular()
+}
 
-	for i := 0; i < imageWidth; i++ {
-		for j := 0; j < imageHeight; j++ {
-			r, g, b, _ := img.At(i, j).RGBA()
-			tfImage[0][j][i][0] = convertValue(r)
-			tfImage[0][j][i][1] = convertValue(g)
-			tfImage[0][j][i][2] = convertValue(b)
-		}
-	}
-	// pre whiten image
-	mean, std := meanStd(tfImage[0])
-	tensor, err := tf.NewTensor(tfImage)
+func loadSavedModel(modelPath string, tags []string) (*tf.Graph, *tf.Session, error) {
+	model, err := tf.LoadSavedModel(modelPath, tags, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return preWhitenImage(tensor, mean, std)
+	return model.Graph, model.Session, nil
 }
-*/
 
-func makeTensorFromImage(img image.Image, imageWidth int, imageHeight int) (*tf.Tensor, error) {
-	var buf bytes.Buffer
-	if err := jpeg.Encode(&buf, img, nil); err != nil {
-		return nil, err
-	}
-	tensor, err := tf.NewTensor(buf.String())
+func loadFrozenGraph(modelPath string) (*tf.Graph, *tf.Session, error) {
+	buf, err := os.ReadFile(modelPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	graph, input, output, err := makeTransformImageGraph(int32(imageWidth), int32(imageHeight))
-	if err != nil {
-		return nil, err
+	graph := tf.NewGraph()
+	if err := graph.Import(buf, ""); err != nil {
+		return nil, nil, err
 	}
 	session, err := tf.NewSession(graph, nil)
 	if err != nil {
-		return nil, err
-	}
-	defer session.Close()
-	out, err := session.Run(
-		map[tf.Output]*tf.Tensor{input: tensor},
-		[]tf.Output{output},
-		nil)
-	if err != nil {
-		return nil, err
-	}
-	if len(out) < 1 || len(out[0].Value().([][][][]float32)) < 1 {
-		return nil, errors.New("invalid output")
+		return nil, nil, err
 	}
-	mean, std := meanStd(out[0].Value().([][][][]float32)[0])
-	return preWhitenImage(out[0], mean, std)
+	return graph, session, nil
 }
 
-// Creates a graph to decode, rezise and normalize an image
-func makeTransformImageGraph(width int32, height int32) (graph *tf.Graph, input, output tf.Output, err error) {
-	s := op.NewScope()
-	input = op.Placeholder(s, tf.String)
-	// Decode PNG or JPEG
-	decode := op.DecodeJpeg(s, input, op.DecodeJpegChannels(3))
-	// Div and Sub perform (value-Mean)/Scale for each pixel
-	output = op.ResizeBilinear(s,
-		// Create a batch containing a single image
-		op.ExpandDims(s,
-			// Use decoded pixel values
-			op.Cast(s, decode, tf.Float),
-			op.Const(s.SubScope("make_batch"), int32(0))),
-		op.Const(s.SubScope("size"), []int32{height, width}),
-	)
-	graph, err = s.Finalize()
-	return graph, input, output, err
-}
-
-func preWhitenImage(img *tf.Tensor, mean, std float32) (*tf.Tensor, error) {
-	s := op.NewScope()
-	pimg := op.Placeholder(s, tf.Float, op.PlaceholderShape(tf.MakeShape(1, -1, -1, 3)))
+func (t *Net) getEmbeddings(imgs []image.Image) ([][]float32, error) {
+	tensor, err := makeBatchTensorFromImages(imgs, t.manifest.Width, t.manifest.Height, t.manifest.Normalization)
 
-	out := op.Mul(s, op.Sub(s, pimg, op.Const(s.SubScope("mean"), mean)),
-		op.Const(s.SubScope("scale"), float32(1.0)/std))
-	outs, err := runScope(s, map[tf.Output]*tf.Tensor{pimg: img}, []tf.Output{out})
 	if err != nil {
+		// log.Printf("faces: failed to convert image to tensor: %v\n", err)
 		return nil, err
 	}
 
-	return outs[0], nil
-}
-
-func runScope(s *op.Scope, inputs map[tf.Output]*tf.Tensor, outputs []tf.Output) ([]*tf.Tensor, error) {
-	graph, err := s.Finalize()
-	if err != nil {
-		return nil, err
+	feeds := map[tf.Output]*tf.Tensor{
+		t.graph.Operation(t.manifest.Input).Output(0): tensor,
 	}
 
-	session, err := tf.NewSession(graph, nil)
-	if err != nil {
-		return nil, err
+	if t.manifest.PhaseTrain != "" {
+		trainPhaseBoolTensor, err := tf.NewTensor(false)
+		if err != nil {
+			return nil, NewError(InferenceFailedErr, fmt.Sprintf("inference failed, %v", err))
+		}
+		feeds[t.graph.Operation(t.manifest.PhaseTrain).Output(0)] = trainPhaseBoolTensor
 	}
-	defer session.Close()
-	return session.Run(inputs, outputs, nil)
-}
 
-func convertValue(value uint32) float32 {
-	return (float32(value>>8) - float32(127.5)) / float32(127.5)
-}
+	output, err := t.session.Run(
+		feeds,
+		[]tf.Output{
+			t.graph.Operation(t.manifest.Output).Output(0),
+		},
+		nil)
 
-func meanStd(img [][][]float32) (mean float32, std float32) {
-	count := len(img) * len(img[0]) * len(img[0][0])
-	for _, x := range img {
-		for _, y := range x {
-			for _, z := range y {
-				mean += z
-			}
-		}
+	if err != nil {
+		// log.Printf("faces: %s\n", err)
+		return nil, NewError(InferenceFailedErr, fmt.Sprintf("inference failed, %v", err))
 	}
-	mean /= float32(count)
 
-	for _, x := range img {
-		for _, y := range x {
-			for _, z := range y {
-				std += (z - mean) * (z - mean)
-			}
-		}
+	if len(output) < 1 {
+		return nil, NewError(InferenceFailedErr, "inference failed, no output")
 	}
-
-	xstd := math.Sqrt(float64(std) / float64(count-1))
-	minstd := 1.0 / math.Sqrt(float64(count))
-	if xstd < minstd {
-		xstd = minstd
+	embeddings, ok := output[0].Value().([][]float32)
+	if !ok {
+		return nil, NewError(InferenceFailedErr, "inference failed, unexpected output shape")
 	}
-
-	std = float32(xstd)
-	return
+	for _, embedding := range embeddings {
+		if t.manifest.Dimension > 0 && len(embedding) != t.manifest.Dimension {
+			return nil, NewError(InferenceFailedErr, fmt.Sprintf("inference failed, expect %d-d embedding, got %d-d", t.manifest.Dimension, len(embedding)))
+		}
+	}
+	return embeddings, nil
 }
@@ -0,0 +1,28 @@
+package core
+
+// NetOption represents Net option interface
+type NetOption interface {
+	apply(*Net)
+}
+
+type netOptionFunc func(*Net)
+
+func (fn netOptionFunc) apply(t *Net) {
+	fn(t)
+}
+
+// WithL2Normalize scales embeddings to unit length at Net output.
+// Distances between normalized embeddings are in [0, 2], so people must be trained with the same setting.
+func WithL2Normalize(normalize bool) NetOption {
+	return netOptionFunc(func(t *Net) {
+		t.l2Normalize = normalize
+	})
+}
+
+// WithFlip embeds both face crops and their mirror images in one batch, and averages the two embeddings.
+// The averaged embeddings are L2 normalized.
+func WithFlip(flip bool) NetOption {
+	return netOptionFunc(func(t *Net) {
+		t.flip = flip
+	})
+}
@@ -8,33 +8,36 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/bububa/facenet/imageutil"
 )
 
 var modelPath, _ = filepath.Abs("../models/facenet")
 
-func TestNet(t *testing.T) {
-	expected := map[string]int{
-		"1.jpg":  1,
-		"2.jpg":  1,
-		"3.jpg":  1,
-		"4.jpg":  1,
-		"5.jpg":  1,
-		"6.jpg":  1,
-		"7.jpg":  0,
-		"8.jpg":  0,
-		"9.jpg":  0,
-		"10.jpg": 0,
-		"11.jpg": 0,
-		"12.jpg": 1,
-		"13.jpg": 0,
-		"14.jpg": 0,
-		"15.jpg": 0,
-		"16.jpg": 1,
-		"17.jpg": 2,
-		"18.jpg": 2,
-		"19.jpg": 0,
-	}
+// netExpected represents the number of faces expected in testdata images
+var netExpected = map[string]int{
+	"1.jpg":  1,
+	"2.jpg":  1,
+	"3.jpg":  1,
+	"4.jpg":  1,
+	"5.jpg":  1,
+	"6.jpg":  1,
+	"7.jpg":  0,
+	"8.jpg":  0,
+	"9.jpg":  0,
+	"10.jpg": 0,
+	"11.jpg": 0,
+	"12.jpg": 1,
+	"13.jpg": 0,
+	"14.jpg": 0,
+	"15.jpg": 0,
+	"16.jpg": 1,
+	"17.jpg": 2,
+	"18.jpg": 2,
+	"19.jpg": 0,
+}
 
+func TestNet(t *testing.T) {
 	faceindices := map[string][]int{
 		"18.jpg": {1, 0},
 		"1.jpg":  {2},
@@ -92,7 +95,7 @@ func TestNet(t *testing.T) {
 				}
 			}
 
-			if i, ok := expected[baseName]; ok {
+			if i, ok := netExpected[baseName]; ok {
 				assert.Equal(t, i, faces.Count())
 
 				if faces.Count() == 0 {
@@ -119,7 +122,10 @@ func TestNet(t *testing.T) {
 			if i >= j {
 				continue
 			}
-			dist := EuclideanDistance(embeddings[i], embeddings[j])
+			dist, err := EuclideanDistance(embeddings[i], embeddings[j])
+			if err != nil {
+				t.Fatal(err)
+			}
 			t.Logf("Dist for %d %d (faces are %d %d) is %f", i, j, faceindexToPersonid[i], faceindexToPersonid[j], dist)
 			if faceindexToPersonid[i] == faceindexToPersonid[j] {
 				if dist < 1.21 {
@@ -139,3 +145,67 @@ func TestNet(t *testing.T) {
 	// 3 out of 55 with the 1.21 threshold
 	assert.Equal(t, 52, correct)
 }
+
+func TestNet_EmbedBatch(t *testing.T) {
+	img := loadTestImage(t, "../testdata/18.jpg")
+	src := imageutil.NormalizeImage(img, MaxImageSize)
+	faces, err := Extract(src, false, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thumbs := make([]image.Image, 0, len(faces))
+	for _, f := range faces {
+		thumbs = append(thumbs, imageutil.Thumb(src, f.CropArea(), CropSize))
+	}
+
+	faceNet := NewNet(modelPath)
+	embeddings, err := faceNet.EmbedBatch(thumbs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, embeddings, len(thumbs))
+
+	for i, thumb := range thumbs {
+		embedding, err := faceNet.Embed(thumb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dist, err := EuclideanDistance(embedding, embeddings[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.InDelta(t, 0, dist, 1e-4)
+	}
+}
+
+func TestNet_Close(t *testing.T) {
+	faceNet := NewNet(modelPath)
+	if err := faceNet.Warmup(); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, faceNet.ModelLoaded())
+
+	assert.NoError(t, faceNet.Close())
+	assert.False(t, faceNet.ModelLoaded())
+	assert.NoError(t, faceNet.Close())
+
+	// the model is loaded again on next use
+	_, err := faceNet.Embed(testThumb(t))
+	assert.NoError(t, err)
+	assert.True(t, faceNet.ModelLoaded())
+	faceNet.Close()
+}
+
+func TestAverageFlipped(t *testing.T) {
+	embeddings := [][]float32{
+		{1, 0},
+		{0, 2},
+		{0, 1},
+		{0, 4},
+	}
+	ret := averageFlipped(embeddings)
+	assert.Len(t, ret, 2)
+	assert.InDeltaSlice(t, []float32{0.70710678, 0.70710678}, ret[0], 1e-6)
+	assert.InDeltaSlice(t, []float32{0, 1}, ret[1], 1e-6)
+}
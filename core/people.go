@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/montanaflynn/stats"
@@ -18,10 +19,36 @@ func LoadPeople(r io.Reader, people *People) error {
 	if err = proto.Unmarshal(buf, people); err != nil {
 		return err
 	}
+	if err = people.Validate(); err != nil {
+		return err
+	}
 	people.Setup()
 	return nil
 }
 
+// Dim returns the embedding dimension of people, 0 if there is no embedding
+func (people *People) Dim() int {
+	for _, person := range people.GetList() {
+		for _, embedding := range person.GetEmbeddings() {
+			return len(embedding.GetValue())
+		}
+	}
+	return 0
+}
+
+// Validate checks all embeddings of people share one dimension
+func (people *People) Validate() error {
+	dim := people.Dim()
+	for _, person := range people.GetList() {
+		for _, embedding := range person.GetEmbeddings() {
+			if l := len(embedding.GetValue()); l != dim {
+				return NewError(DimensionMismatchErr, fmt.Sprintf("person %s has a %d-d embedding, expect %d-d", person.GetName(), l, dim))
+			}
+		}
+	}
+	return nil
+}
+
 // Save save people to a model file
 func (people *People) Save(w io.Writer) error {
 	people.Setup()
@@ -33,85 +60,199 @@ func (people *People) Save(w io.Writer) error {
 	return err
 }
 
-// Setup recalculate people's center and collisions
+// Setup recalculate people's center and collisions, radii of a former metric are reset
 func (people *People) Setup() {
+	metric := people.GetMetric()
 	for _, person := range people.GetList() {
-		person.ReCenter()
+		person.Radius = 0
+		person.CollisionRadius = 0
+		person.ReCenterMetric(metric)
 	}
 	people.ResolveCollisions()
 }
 
+// SetMetric set the distance metric of people and recalculate centers and collisions.
+// The match distance is reset to the default of the metric.
+func (people *People) SetMetric(metric Metric) {
+	people.Metric = metric
+	people.MatchDist = 0
+	people.Setup()
+}
+
+// MatchThreshold returns the match distance of people in metric units
+func (people *People) MatchThreshold() float64 {
+	if d := people.GetMatchDist(); d > 0 {
+		return d
+	}
+	return people.GetMetric().MatchDist()
+}
+
 // Delete delete a person from people
 func (people *People) Delete(name string) bool {
-	var deleted bool
+	return people.remove(name) != nil
+}
+
+// remove removes a person by name from people and returns it, nil if not found
+func (people *People) remove(name string) *Person {
 	name = strings.TrimSpace(name)
 	list := people.GetList()
 	l := len(list)
 	for i := 0; i < l; i++ {
 		if list[i].GetName() == name {
+			person := list[i]
 			people.List = append(people.List[:i], people.List[i+1:]...)
-			deleted = true
-			break
+			return person
 		}
 	}
-	return deleted
+	return nil
 }
 
 // Append append person to people and update when duplicate
 func (people *People) Append(items ...*Person) {
+	people.appendPersons(items)
+}
+
+// appendPersons appends items to people, returns the persons added and the persons replaced by items of the same name
+func (people *People) appendPersons(items []*Person) (added []*Person, replaced []*Person) {
 	list := people.GetList()
 	exists := make(map[string]struct{}, len(list))
 	for _, p := range list {
 		exists[p.GetName()] = struct{}{}
 	}
 	replaces := make(map[string]*Person, len(items))
+	added = make([]*Person, 0, len(items))
 	for _, item := range items {
 		if _, found := exists[item.GetName()]; !found {
 			list = append(list, item)
+			added = append(added, item)
 			exists[item.GetName()] = struct{}{}
 		} else {
 			replaces[item.GetName()] = item
 		}
 	}
+	replaced = make([]*Person, 0, len(replaces))
 	l := len(list)
 	for i := 0; i < l; i++ {
 		name := list[i].GetName()
 		if person, found := replaces[name]; found {
+			replaced = append(replaced, list[i])
+			added = append(added, person)
 			list[i] = person
 		}
 	}
 	people.List = list
+	return added, replaced
 }
 
 // Match match a person from people based on embedding
 func (people *People) Match(embedding []float32) (*Person, float64, error) {
+	if err := people.checkDim(embedding); err != nil {
+		return nil, -1, err
+	}
 	person, dist := people.Nearest(embedding)
+	return person, dist, people.checkMatch(person, dist)
+}
+
+// MatchTopK returns the k persons nearest to embedding sorted by distance, with the reason each one does not match
+func (people *People) MatchTopK(embedding []float32, k int) ([]Candidate, error) {
+	if err := people.checkDim(embedding); err != nil {
+		return nil, err
+	}
+	persons, dists := people.NearestK(embedding, k)
+	return people.candidates(persons, dists)
+}
+
+// checkDim returns DimensionMismatchErr if embedding can not be compared with embeddings of people
+func (people *People) checkDim(embedding []float32) error {
+	if dim := people.Dim(); dim > 0 && dim != len(embedding) {
+		return NewError(DimensionMismatchErr, fmt.Sprintf("embedding dimensions mismatch, %d != %d", len(embedding), dim))
+	}
+	return nil
+}
+
+// candidates returns nearest persons and their distances as candidates with match verdicts
+func (people *People) candidates(persons []*Person, dists []float64) ([]Candidate, error) {
+	if len(persons) == 0 {
+		return nil, NewError(NothingMatchErr, "no match results")
+	}
+	ret := make([]Candidate, 0, len(persons))
+	for i, person := range persons {
+		ret = append(ret, Candidate{
+			Person:   person,
+			Distance: dists[i],
+			Err:      people.checkMatch(person, dists[i]),
+		})
+	}
+	return ret, nil
+}
+
+// checkMatch returns the reason embeddings of person do not match a face at dist, nil if matched
+func (people *People) checkMatch(person *Person, dist float64) error {
 	// Any reasons embeddings do not match this face?
 	switch {
 	case dist < 0:
 		// Should never happen.
-		return person, dist, NewError(NegativeDistanceMatchErr, fmt.Sprintf("distance is too small, %f", dist))
-	case dist > (person.GetRadius() + MatchDist):
+		return NewError(NegativeDistanceMatchErr, fmt.Sprintf("distance is too small, %f", dist))
+	case dist > (person.GetRadius() + people.MatchThreshold()):
 		// Too far.
-		return person, dist, NewError(TooFarMatchErr, fmt.Sprintf("distance is too far, %f", dist))
-	case person.GetCollisionRadius() > 0.1 && dist > person.GetCollisionRadius():
+		return NewError(TooFarMatchErr, fmt.Sprintf("distance is too far, %f", dist))
+	case person.GetCollisionRadius() > people.GetMetric().collisionFloor() && dist > person.GetCollisionRadius():
 		// log.Printf("person: %s, collision: %f, dist: %f\n", person.Name, collisionRadius, dist)
 		// Within radius of reported collisions.
-		return person, dist, NewError(CollisionMatchErr, fmt.Sprintf("distance(%f) is larger than collision radius(%f)", dist, person.GetCollisionRadius()))
+		return NewError(CollisionMatchErr, fmt.Sprintf("distance(%f) is larger than collision radius(%f)", dist, person.GetCollisionRadius()))
+	}
+	return nil
+}
+
+// NearestK returns the k nearest persons in people sorted by distance
+func (people *People) NearestK(embedding []float32, k int) ([]*Person, []float64) {
+	if k <= 0 {
+		return nil, nil
+	}
+	metric := people.GetMetric()
+	persons := make([]*Person, 0, len(people.GetList()))
+	dists := make(map[*Person]float64, len(people.GetList()))
+	for _, person := range people.GetList() {
+		for _, embeddingObj := range person.GetEmbeddings() {
+			d, err := metric.Distance(embedding, embeddingObj.GetValue())
+			if err != nil {
+				continue
+			}
+			if dist, found := dists[person]; !found {
+				persons = append(persons, person)
+				dists[person] = d
+			} else if d < dist {
+				dists[person] = d
+			}
+		}
+	}
+	sort.SliceStable(persons, func(i, j int) bool {
+		return dists[persons[i]] < dists[persons[j]]
+	})
+	if len(persons) > k {
+		persons = persons[:k]
 	}
-	return person, dist, nil
+	ret := make([]float64, 0, len(persons))
+	for _, person := range persons {
+		ret = append(ret, dists[person])
+	}
+	return persons, ret
 }
 
 // Nearest returns nearest person in people
 func (people *People) Nearest(embedding []float32) (*Person, float64) {
 	var ret *Person
 	dist := -1.0
+	metric := people.GetMetric()
 
 	// Find the nearest person for this data point
 	for _, person := range people.GetList() {
 		// d := EuclideanDistance(person.GetCenter())
 		for _, embeddingObj := range person.GetEmbeddings() {
-			d := EuclideanDistance(embedding, embeddingObj.GetValue())
+			d, err := metric.Distance(embedding, embeddingObj.GetValue())
+			if err != nil {
+				continue
+			}
 			if ret == nil || d < dist {
 				dist = d
 				ret = person
@@ -125,13 +266,14 @@ func (people *People) Nearest(embedding []float32) (*Person, float64) {
 func (people *People) Neighbour(embedding []float32, nearest *Person) (*Person, float64) {
 	var ret *Person
 	var dist float64
+	metric := people.GetMetric()
 
 	// Find the nearest person for this data point
 	for _, person := range people.GetList() {
 		if person.Equal(nearest) {
 			continue
 		}
-		d := person.AverageDistance(embedding)
+		d := person.AverageDistanceMetric(embedding, metric)
 		if ret == nil || d < dist {
 			dist = d
 			ret = person
@@ -143,12 +285,14 @@ func (people *People) Neighbour(embedding []float32, nearest *Person) (*Person,
 // ResolveCollisions resolves collisions of different subject's faces.
 func (people *People) ResolveCollisions() {
 	list := people.GetList()
+	metric := people.GetMetric()
+	matchDist := people.MatchThreshold()
 	for _, f1 := range list {
 		for _, f2 := range list {
 			if f1.Equal(f2) {
 				continue
 			}
-			f1.ResolveCollision(f2)
+			f1.ResolveCollisionMetric(f2, metric, matchDist)
 		}
 	}
 }
@@ -165,11 +309,21 @@ func (person *Person) Append(embedding []float32) {
 
 // ReCenter recalculate person's center
 func (person *Person) ReCenter() {
-	person.Center, person.Radius, _ = person.CalcCenter()
+	person.ReCenterMetric(Metric_EUCLIDEAN)
+}
+
+// ReCenterMetric recalculate person's center, radius is in metric units
+func (person *Person) ReCenterMetric(metric Metric) {
+	person.Center, person.Radius, _ = person.CalcCenterMetric(metric)
 }
 
 // CalcCenter returns the center coordinates of a set of people
 func (person *Person) CalcCenter() (result []float32, radius float64, count int) {
+	return person.CalcCenterMetric(Metric_EUCLIDEAN)
+}
+
+// CalcCenterMetric returns the center coordinates of a set of people, radius is in metric units
+func (person *Person) CalcCenterMetric(metric Metric) (result []float32, radius float64, count int) {
 	embeddings := person.GetEmbeddings()
 	count = len(embeddings)
 	// No embeddings?
@@ -189,10 +343,13 @@ func (person *Person) CalcCenter() (result []float32, radius float64, count int)
 
 	// The mean of a set of vectors is calculated component-wise.
 	for i := 0; i < dim; i++ {
-		values := make(stats.Float64Data, count)
+		values := make(stats.Float64Data, 0, count)
 
 		for j := 0; j < count; j++ {
-			values[j] = float64(embeddings[j].GetValue()[i])
+			// Skip embeddings with a different dimension.
+			if value := embeddings[j].GetValue(); len(value) == dim {
+				values = append(values, float64(value[i]))
+			}
 		}
 
 		if m, err := stats.Mean(values); err != nil {
@@ -202,10 +359,10 @@ func (person *Person) CalcCenter() (result []float32, radius float64, count int)
 		}
 	}
 
-	// Radius is the max embedding distance + 0.01 from result.
+	// Radius is the max embedding distance + margin from result.
 	for _, emb := range embeddings {
-		if d := EuclideanDistance(result, emb.GetValue()); d > radius {
-			radius = d + 0.01
+		if d, err := metric.Distance(result, emb.GetValue()); err == nil && d > radius {
+			radius = d + metric.radiusMargin()
 		}
 	}
 
@@ -214,12 +371,17 @@ func (person *Person) CalcCenter() (result []float32, radius float64, count int)
 
 // AverageDistance returns the average distance between o and all people
 func (person *Person) AverageDistance(embedding []float32) float64 {
+	return person.AverageDistanceMetric(embedding, Metric_EUCLIDEAN)
+}
+
+// AverageDistanceMetric returns the average distance in metric units between o and all people
+func (person *Person) AverageDistanceMetric(embedding []float32, metric Metric) float64 {
 	var d float64
 	var l int
 
 	for _, p := range person.GetEmbeddings() {
-		dist := EuclideanDistance(p.GetValue(), embedding)
-		if dist == 0 {
+		dist, err := metric.Distance(p.GetValue(), embedding)
+		if err != nil || dist == 0 {
 			continue
 		}
 
@@ -235,6 +397,11 @@ func (person *Person) AverageDistance(embedding []float32) float64 {
 
 // Match match embedding with a person
 func (person *Person) Match(embedding []float32) (bool, float64) {
+	return person.MatchMetric(embedding, Metric_EUCLIDEAN, MatchDist)
+}
+
+// MatchMetric match embedding with a person, matchDist is in metric units
+func (person *Person) MatchMetric(embedding []float32, metric Metric, matchDist float64) (bool, float64) {
 	personEmbeddings := person.GetEmbeddings()
 	var dist float64 = -1
 
@@ -245,7 +412,7 @@ func (person *Person) Match(embedding []float32) (bool, float64) {
 
 	for _, personEmbedding := range personEmbeddings {
 		// Calculate smallest distance to embeddings.
-		if d := EuclideanDistance(embedding, personEmbedding.GetValue()); d < dist || dist < 0 {
+		if d, err := metric.Distance(embedding, personEmbedding.GetValue()); err == nil && (d < dist || dist < 0) {
 			dist = d
 		}
 	}
@@ -255,10 +422,10 @@ func (person *Person) Match(embedding []float32) (bool, float64) {
 	case dist < 0:
 		// Should never happen.
 		return false, dist
-	case dist > (person.Radius + MatchDist):
+	case dist > (person.Radius + matchDist):
 		// Too far.
 		return false, dist
-	case person.GetCollisionRadius() > 0.1 && dist > person.GetCollisionRadius():
+	case person.GetCollisionRadius() > metric.collisionFloor() && dist > person.GetCollisionRadius():
 		// Within radius of reported collisions.
 		return false, dist
 	}
@@ -269,9 +436,15 @@ func (person *Person) Match(embedding []float32) (bool, float64) {
 
 // ResolveCollision calculate CollisionRadius for a person
 func (person *Person) ResolveCollision(p2 *Person) {
+	person.ResolveCollisionMetric(p2, Metric_EUCLIDEAN, MatchDist)
+}
+
+// ResolveCollisionMetric calculate CollisionRadius for a person, matchDist is in metric units
+func (person *Person) ResolveCollisionMetric(p2 *Person, metric Metric, matchDist float64) {
+	margin := metric.radiusMargin()
 	for _, embedding := range p2.GetEmbeddings() {
-		if matched, dist := person.Match(embedding.GetValue()); matched && (person.CollisionRadius < 0.1 || person.CollisionRadius < dist-0.01) {
-			person.CollisionRadius = dist - 0.01
+		if matched, dist := person.MatchMetric(embedding.GetValue(), metric, matchDist); matched && (person.CollisionRadius < metric.collisionFloor() || person.CollisionRadius < dist-margin) {
+			person.CollisionRadius = dist - margin
 		}
 	}
 }
@@ -0,0 +1,428 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// PeopleIndex is an exact vector index over the embeddings of people, it finds the same persons as People.Nearest faster.
+// People must be changed through the index by Append, Delete and SetMetric, which keep it up to date without checking people on searches.
+// Rebuild must be called after people were changed otherwise.
+type PeopleIndex struct {
+	mutex  sync.RWMutex
+	people *People
+	tree   *vpIndex
+}
+
+// NewPeopleIndex returns the vector index of people, it is built on the first search
+func NewPeopleIndex(people *People) *PeopleIndex {
+	return &PeopleIndex{
+		people: people,
+	}
+}
+
+// indexMinRebuild min number of changed embeddings before rebuilding an index
+const indexMinRebuild = 64
+
+// indexItem represents an indexed embedding
+type indexItem struct {
+	person *Person
+	// value the embedding of person
+	value []float32
+	// vector the indexed value, L2 normalized for the cosine metric
+	vector []float32
+}
+
+// vpNode represents a vantage point tree node, items of inside are not farther than radius from the vantage point
+type vpNode struct {
+	item    indexItem
+	radius  float64
+	inside  *vpNode
+	outside *vpNode
+}
+
+// vpIndex is an exact vantage point tree over the embeddings of people.
+// Euclidean distances of indexed vectors rank embeddings in the same order as every Metric:
+// squared euclidean is monotonic and cosine distance is euclidean distance² / 2 of L2 normalized vectors.
+type vpIndex struct {
+	metric Metric
+	dim    int
+	root   *vpNode
+	size   int
+	// pending items appended after the tree was built, scanned linearly
+	pending []indexItem
+	// zeros zero vectors of the cosine metric, scanned linearly
+	zeros []indexItem
+	// removed persons still in the tree
+	removed      map[*Person]struct{}
+	removedItems int
+}
+
+// neighbour represents a person with the distance of its nearest embedding
+type neighbour struct {
+	item indexItem
+	// dist index distance
+	dist float64
+}
+
+// newVPIndex builds the index of people embeddings
+func newVPIndex(people *People) *vpIndex {
+	idx := &vpIndex{
+		metric:  people.GetMetric(),
+		dim:     people.Dim(),
+		removed: make(map[*Person]struct{}),
+	}
+	var tree []indexItem
+	for _, person := range people.GetList() {
+		for _, embedding := range person.GetEmbeddings() {
+			item, ok := idx.newItem(person, embedding.GetValue())
+			if !ok {
+				continue
+			}
+			// zero vectors have no direction, their cosine distance is constant
+			if idx.metric == Metric_COSINE && isZero(item.value) {
+				idx.zeros = append(idx.zeros, item)
+				continue
+			}
+			tree = append(tree, item)
+		}
+	}
+	idx.size = len(tree)
+	idx.root = buildVPTree(tree)
+	return idx
+}
+
+// newItem returns the index item of an embedding, false if its dimension mismatches
+func (idx *vpIndex) newItem(person *Person, value []float32) (indexItem, bool) {
+	if len(value) != idx.dim {
+		return indexItem{}, false
+	}
+	vector := value
+	if idx.metric == Metric_COSINE {
+		vector = L2Normalize(value)
+	}
+	return indexItem{
+		person: person,
+		value:  value,
+		vector: vector,
+	}, true
+}
+
+// buildVPTree builds a vantage point tree, items are reordered
+func buildVPTree(items []indexItem) *vpNode {
+	if len(items) == 0 {
+		return nil
+	}
+	node := &vpNode{item: items[0]}
+	rest := items[1:]
+	if len(rest) == 0 {
+		return node
+	}
+	dists := make([]float64, len(rest))
+	for i := range rest {
+		dists[i] = indexDistance(node.item.vector, rest[i].vector)
+	}
+	sort.Sort(byDistance{items: rest, dists: dists})
+	median := len(rest) / 2
+	node.radius = dists[median]
+	node.inside = buildVPTree(rest[:median+1])
+	node.outside = buildVPTree(rest[median+1:])
+	return node
+}
+
+// byDistance sorts items by their distances to a vantage point
+type byDistance struct {
+	items []indexItem
+	dists []float64
+}
+
+func (s byDistance) Len() int {
+	return len(s.items)
+}
+
+func (s byDistance) Less(i, j int) bool {
+	return s.dists[i] < s.dists[j]
+}
+
+func (s byDistance) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.dists[i], s.dists[j] = s.dists[j], s.dists[i]
+}
+
+// indexDistance returns the euclidean distance between indexed vectors of the same dimension
+func indexDistance(v1 []float32, v2 []float32) float64 {
+	var dist float64
+	for k := range v1 {
+		d := float64(v1[k] - v2[k])
+		dist += d * d
+	}
+	return math.Sqrt(dist)
+}
+
+// isZero returns true if all values of embedding are 0
+func isZero(embedding []float32) bool {
+	for _, v := range embedding {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// distance returns the index distance between a query vector and an item
+func (idx *vpIndex) distance(vector []float32, item indexItem) float64 {
+	if idx.metric == Metric_COSINE && isZero(item.value) {
+		// cosine distance 1 of zero vectors as an index distance
+		return math.Sqrt2
+	}
+	return indexDistance(vector, item.vector)
+}
+
+// nearest returns the k distinct persons with the nearest embeddings sorted by distance, ok is false if embedding can not be searched
+func (idx *vpIndex) nearest(embedding []float32, k int) (ret []neighbour, ok bool) {
+	if len(embedding) != idx.dim || idx.metric == Metric_COSINE && isZero(embedding) {
+		return nil, false
+	}
+	vector := embedding
+	if idx.metric == Metric_COSINE {
+		vector = L2Normalize(embedding)
+	}
+	s := &vpSearch{
+		k:       k,
+		removed: idx.removed,
+	}
+	s.search(idx.root, vector)
+	for _, item := range idx.pending {
+		s.consider(item, idx.distance(vector, item))
+	}
+	for _, item := range idx.zeros {
+		s.consider(item, math.Sqrt2)
+	}
+	return s.top, true
+}
+
+// vpSearch represents a k nearest distinct persons search
+type vpSearch struct {
+	k       int
+	removed map[*Person]struct{}
+	// top nearest persons sorted by distance
+	top []neighbour
+}
+
+// tau returns the distance under which an item may enter the top persons
+func (s *vpSearch) tau() float64 {
+	if len(s.top) < s.k {
+		return math.Inf(1)
+	}
+	return s.top[len(s.top)-1].dist
+}
+
+// consider adds item to the top persons if it is nearer
+func (s *vpSearch) consider(item indexItem, dist float64) {
+	if _, found := s.removed[item.person]; found {
+		return
+	}
+	for i, n := range s.top {
+		if n.item.person == item.person {
+			if dist < n.dist {
+				s.top[i] = neighbour{item: item, dist: dist}
+				s.sort()
+			}
+			return
+		}
+	}
+	if len(s.top) < s.k {
+		s.top = append(s.top, neighbour{item: item, dist: dist})
+	} else if dist < s.tau() {
+		s.top[len(s.top)-1] = neighbour{item: item, dist: dist}
+	} else {
+		return
+	}
+	s.sort()
+}
+
+// sort sorts top persons by distance
+func (s *vpSearch) sort() {
+	sort.SliceStable(s.top, func(i, j int) bool {
+		return s.top[i].dist < s.top[j].dist
+	})
+}
+
+// search visits the nodes which may hold items nearer than tau
+func (s *vpSearch) search(node *vpNode, vector []float32) {
+	if node == nil {
+		return
+	}
+	d := indexDistance(vector, node.item.vector)
+	s.consider(node.item, d)
+	if d <= node.radius {
+		s.search(node.inside, vector)
+		if d+s.tau() >= node.radius {
+			s.search(node.outside, vector)
+		}
+		return
+	}
+	s.search(node.outside, vector)
+	if d-s.tau() <= node.radius {
+		s.search(node.inside, vector)
+	}
+}
+
+// append indexes the embeddings of persons added to people and removes replaced persons
+func (idx *vpIndex) append(added []*Person, replaced []*Person) {
+	for _, person := range replaced {
+		idx.remove(person)
+	}
+	for _, person := range added {
+		// a person appended again replaces itself
+		delete(idx.removed, person)
+		for _, embedding := range person.GetEmbeddings() {
+			if item, ok := idx.newItem(person, embedding.GetValue()); ok {
+				idx.pending = append(idx.pending, item)
+			}
+		}
+	}
+}
+
+// remove removes a person from the index
+func (idx *vpIndex) remove(person *Person) {
+	if _, found := idx.removed[person]; found {
+		return
+	}
+	idx.removed[person] = struct{}{}
+	idx.removedItems += len(person.GetEmbeddings())
+	pending := idx.pending[:0]
+	for _, item := range idx.pending {
+		if item.person != person {
+			pending = append(pending, item)
+		}
+	}
+	idx.pending = pending
+}
+
+// stale returns true if the metric or dimension of people changed or the index changed too much since built, in constant time
+func (idx *vpIndex) stale(people *People) bool {
+	if idx.metric != people.GetMetric() || idx.dim != people.Dim() {
+		return true
+	}
+	return len(idx.pending)+idx.removedItems > idx.size/4+indexMinRebuild
+}
+
+// People returns the indexed people
+func (pi *PeopleIndex) People() *People {
+	return pi.people
+}
+
+// Rebuild builds the index again on the next search, e.g. after people were loaded or embeddings were changed in place
+func (pi *PeopleIndex) Rebuild() {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	pi.tree = nil
+}
+
+// SetMetric set the distance metric of people, see People.SetMetric, the index is built again on the next search
+func (pi *PeopleIndex) SetMetric(metric Metric) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	pi.people.SetMetric(metric)
+	pi.tree = nil
+}
+
+// Append appends persons to people, replacing persons of the same name, and indexes them
+func (pi *PeopleIndex) Append(items ...*Person) {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	added, replaced := pi.people.appendPersons(items)
+	if pi.tree != nil {
+		pi.tree.append(added, replaced)
+	}
+}
+
+// Delete deletes a person by name from people and the index
+func (pi *PeopleIndex) Delete(name string) bool {
+	pi.mutex.Lock()
+	defer pi.mutex.Unlock()
+	person := pi.people.remove(name)
+	if person == nil {
+		return false
+	}
+	if pi.tree != nil {
+		pi.tree.append(nil, []*Person{person})
+	}
+	return true
+}
+
+// search returns the k nearest persons of embedding in the up to date tree, ok is false if embedding can not be searched
+func (pi *PeopleIndex) search(embedding []float32, k int) ([]neighbour, bool) {
+	for {
+		pi.mutex.RLock()
+		if pi.tree != nil && !pi.tree.stale(pi.people) {
+			defer pi.mutex.RUnlock()
+			return pi.tree.nearest(embedding, k)
+		}
+		pi.mutex.RUnlock()
+		pi.mutex.Lock()
+		if pi.tree == nil || pi.tree.stale(pi.people) {
+			pi.tree = newVPIndex(pi.people)
+		}
+		pi.mutex.Unlock()
+	}
+}
+
+// Nearest returns nearest person in people
+func (pi *PeopleIndex) Nearest(embedding []float32) (*Person, float64) {
+	neighbours, ok := pi.search(embedding, 1)
+	if !ok {
+		return pi.people.Nearest(embedding)
+	}
+	if len(neighbours) == 0 {
+		return nil, -1
+	}
+	dist, err := pi.people.GetMetric().Distance(embedding, neighbours[0].item.value)
+	if err != nil {
+		return nil, -1
+	}
+	return neighbours[0].item.person, dist
+}
+
+// NearestK returns the k nearest persons in people sorted by distance
+func (pi *PeopleIndex) NearestK(embedding []float32, k int) ([]*Person, []float64) {
+	if k <= 0 {
+		return nil, nil
+	}
+	neighbours, ok := pi.search(embedding, k)
+	if !ok {
+		return pi.people.NearestK(embedding, k)
+	}
+	metric := pi.people.GetMetric()
+	persons := make([]*Person, 0, len(neighbours))
+	dists := make([]float64, 0, len(neighbours))
+	for _, n := range neighbours {
+		dist, err := metric.Distance(embedding, n.item.value)
+		if err != nil {
+			continue
+		}
+		persons = append(persons, n.item.person)
+		dists = append(dists, dist)
+	}
+	return persons, dists
+}
+
+// Match match a person from people based on embedding
+func (pi *PeopleIndex) Match(embedding []float32) (*Person, float64, error) {
+	if err := pi.people.checkDim(embedding); err != nil {
+		return nil, -1, err
+	}
+	person, dist := pi.Nearest(embedding)
+	return person, dist, pi.people.checkMatch(person, dist)
+}
+
+// MatchTopK returns the k persons nearest to embedding sorted by distance, with the reason each one does not match
+func (pi *PeopleIndex) MatchTopK(embedding []float32, k int) ([]Candidate, error) {
+	if err := pi.people.checkDim(embedding); err != nil {
+		return nil, err
+	}
+	persons, dists := pi.NearestK(embedding, k)
+	return pi.people.candidates(persons, dists)
+}
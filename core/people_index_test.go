@@ -0,0 +1,226 @@
+package core
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// randomPeople returns persons with embeddings spread around a random center of each person
+func randomPeople(rnd *rand.Rand, persons int, embeddings int, dim int) *People {
+	people := new(People)
+	for i := 0; i < persons; i++ {
+		people.List = append(people.List, randomPerson(rnd, fmt.Sprintf("person-%d", i), embeddings, dim))
+	}
+	return people
+}
+
+func randomPerson(rnd *rand.Rand, name string, embeddings int, dim int) *Person {
+	center := randomEmbedding(rnd, dim)
+	person := &Person{Name: name}
+	for j := 0; j < embeddings; j++ {
+		value := make([]float32, dim)
+		for k := range value {
+			value[k] = center[k] + float32(rnd.NormFloat64()*0.05)
+		}
+		person.Append(L2Normalize(value))
+	}
+	return person
+}
+
+func randomEmbedding(rnd *rand.Rand, dim int) []float32 {
+	value := make([]float32, dim)
+	for k := range value {
+		value[k] = float32(rnd.NormFloat64())
+	}
+	return L2Normalize(value)
+}
+
+// assertNearestExact checks the index returns the brute force result
+func assertNearestExact(t *testing.T, rnd *rand.Rand, index *PeopleIndex, queries int) {
+	people := index.People()
+	dim := people.Dim()
+	for i := 0; i < queries; i++ {
+		query := randomEmbedding(rnd, dim)
+		if i%2 == 0 {
+			// near an existing person
+			person := people.List[rnd.Intn(len(people.List))]
+			query = person.Embeddings[0].Value
+		}
+		expected, expectedDist := people.Nearest(query)
+		person, dist := index.Nearest(query)
+		if !assert.Equal(t, expected.GetName(), person.GetName()) {
+			return
+		}
+		assert.InDelta(t, expectedDist, dist, 1e-9)
+
+		expectedPersons, expectedDists := people.NearestK(query, 5)
+		persons, dists := index.NearestK(query, 5)
+		if !assert.Equal(t, expectedPersons, persons) {
+			return
+		}
+		assert.InDeltaSlice(t, expectedDists, dists, 1e-9)
+	}
+}
+
+func TestPeopleIndex_Nearest(t *testing.T) {
+	for _, metric := range []Metric{Metric_EUCLIDEAN, Metric_SQUARED_EUCLIDEAN, Metric_COSINE} {
+		t.Run(metric.String(), func(t *testing.T) {
+			rnd := rand.New(rand.NewSource(1))
+			people := randomPeople(rnd, 300, 4, 16)
+			people.Metric = metric
+			index := NewPeopleIndex(people)
+			assertNearestExact(t, rnd, index, 200)
+
+			t.Run("append", func(t *testing.T) {
+				added := make([]*Person, 0, 20)
+				for i := 0; i < 20; i++ {
+					added = append(added, randomPerson(rnd, fmt.Sprintf("added-%d", i), 3, 16))
+				}
+				// replaces an indexed person
+				added = append(added, randomPerson(rnd, "person-0", 3, 16))
+				index.Append(added...)
+				assertNearestExact(t, rnd, index, 200)
+			})
+
+			t.Run("delete", func(t *testing.T) {
+				for i := 1; i < 40; i++ {
+					assert.True(t, index.Delete(fmt.Sprintf("person-%d", i)))
+				}
+				assert.True(t, index.Delete("added-0"))
+				assert.False(t, index.Delete("added-0"))
+				assertNearestExact(t, rnd, index, 200)
+			})
+
+			t.Run("rebuild", func(t *testing.T) {
+				// a list of the same size changed without the index
+				list := make([]*Person, 0, len(people.List))
+				for i := range people.List {
+					list = append(list, randomPerson(rnd, fmt.Sprintf("other-%d", i), 4, 16))
+				}
+				people.List = list
+				index.Rebuild()
+				assertNearestExact(t, rnd, index, 100)
+
+				people.List = people.List[:len(people.List)/2]
+				index.Rebuild()
+				index.Append(randomPerson(rnd, "appended", 2, 16))
+				assertNearestExact(t, rnd, index, 100)
+			})
+
+			t.Run("set metric", func(t *testing.T) {
+				other := Metric_COSINE
+				if metric == Metric_COSINE {
+					other = Metric_EUCLIDEAN
+				}
+				index.SetMetric(other)
+				assert.Equal(t, other, people.GetMetric())
+				assertNearestExact(t, rnd, index, 100)
+			})
+		})
+	}
+
+	t.Run("cosine zero vectors", func(t *testing.T) {
+		people := testPeople()
+		people.Metric = Metric_COSINE
+		index := NewPeopleIndex(people)
+		person, dist := index.Nearest([]float32{0, 0, 1})
+		assert.Equal(t, "b", person.GetName())
+		assert.InDelta(t, 1-1/1.7320508, dist, 1e-6)
+		// the zero vector of a is nearer than the opposite directions
+		person, dist = index.Nearest([]float32{-1, -1, -1})
+		assert.Equal(t, "a", person.GetName())
+		assert.InDelta(t, 1, dist, 1e-9)
+	})
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		people := testPeople()
+		people.Setup()
+		index := NewPeopleIndex(people)
+		person, dist := index.Nearest([]float32{0, 0})
+		assert.Nil(t, person)
+		assert.Equal(t, -1.0, dist)
+		_, _, err := index.Match([]float32{0, 0})
+		if assert.Error(t, err) {
+			assert.Equal(t, DimensionMismatchErr, err.(Error).Code)
+		}
+	})
+}
+
+func TestPeopleIndex_MatchTopK(t *testing.T) {
+	people := testPeople()
+	people.Setup()
+	index := NewPeopleIndex(people)
+	for _, query := range [][]float32{{0.05, 0, 0}, {0.9, 1, 1}} {
+		expected, err := people.MatchTopK(query, 2)
+		candidates, indexErr := index.MatchTopK(query, 2)
+		assert.Equal(t, err, indexErr)
+		assert.Equal(t, expected, candidates)
+
+		person, dist, err := people.Match(query)
+		indexPerson, indexDist, indexErr := index.Match(query)
+		assert.Equal(t, person, indexPerson)
+		assert.Equal(t, dist, indexDist)
+		assert.Equal(t, err, indexErr)
+	}
+}
+
+func TestPeopleIndex_Concurrent(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	people := randomPeople(rnd, 100, 2, 8)
+	index := NewPeopleIndex(people)
+	queries := make([][]float32, 8)
+	for i := range queries {
+		queries[i] = randomEmbedding(rnd, 8)
+	}
+	var wg sync.WaitGroup
+	for _, query := range queries {
+		wg.Add(1)
+		go func(query []float32) {
+			defer wg.Done()
+			expected, _ := people.Nearest(query)
+			person, _ := index.Nearest(query)
+			assert.Equal(t, expected, person)
+		}(query)
+	}
+	wg.Wait()
+}
+
+func BenchmarkPeopleIndex_Nearest(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	people := randomPeople(rnd, 5000, 4, 128)
+	index := NewPeopleIndex(people)
+	// new faces of known persons
+	queries := make([][]float32, 100)
+	for i := range queries {
+		person := people.List[rnd.Intn(len(people.List))]
+		query := make([]float32, 128)
+		for k, v := range person.Embeddings[0].Value {
+			query[k] = v + float32(rnd.NormFloat64()*0.05)
+		}
+		queries[i] = L2Normalize(query)
+	}
+
+	// builds the tree before timing
+	index.Nearest(queries[0])
+
+	// a query of the index includes its lock and staleness check
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index.Nearest(queries[i%len(queries)])
+		}
+	})
+	b.Run("tree", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			index.tree.nearest(queries[i%len(queries)], 1)
+		}
+	})
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			people.Nearest(queries[i%len(queries)])
+		}
+	})
+}
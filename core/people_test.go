@@ -0,0 +1,154 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPeople() *People {
+	return &People{
+		List: []*Person{
+			{
+				Name: "a",
+				Embeddings: []*Person_Embedding{
+					{Value: []float32{0, 0, 0}},
+					{Value: []float32{0.1, 0, 0}},
+				},
+			},
+			{
+				Name: "b",
+				Embeddings: []*Person_Embedding{
+					{Value: []float32{1, 1, 1}},
+					{Value: []float32{1, 1.1, 1}},
+				},
+			},
+		},
+	}
+}
+
+func TestLoadPeople(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := testPeople().Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		people := new(People)
+		assert.Nil(t, LoadPeople(&buf, people))
+		assert.Equal(t, 3, people.Dim())
+	})
+	t.Run("dimension mismatch", func(t *testing.T) {
+		src := testPeople()
+		src.List[1].Append([]float32{1, 1})
+		var buf bytes.Buffer
+		if err := src.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		err := LoadPeople(&buf, new(People))
+		if assert.Error(t, err) {
+			assert.Equal(t, DimensionMismatchErr, err.(Error).Code)
+		}
+	})
+}
+
+func TestPeople_Match(t *testing.T) {
+	people := testPeople()
+	people.Setup()
+	t.Run("matched", func(t *testing.T) {
+		person, _, err := people.Match([]float32{0.05, 0, 0})
+		assert.Nil(t, err)
+		assert.Equal(t, "a", person.GetName())
+	})
+	t.Run("dimension mismatch", func(t *testing.T) {
+		_, _, err := people.Match([]float32{0.05, 0})
+		if assert.Error(t, err) {
+			assert.Equal(t, DimensionMismatchErr, err.(Error).Code)
+		}
+	})
+}
+
+func TestPeople_MatchTopK(t *testing.T) {
+	people := testPeople()
+	people.List = append(people.List, &Person{
+		Name: "c",
+		Embeddings: []*Person_Embedding{
+			{Value: []float32{0.3, 0, 0}},
+		},
+	})
+	people.Setup()
+
+	t.Run("top k", func(t *testing.T) {
+		candidates, err := people.MatchTopK([]float32{0.05, 0, 0}, 2)
+		if assert.NoError(t, err) && assert.Len(t, candidates, 2) {
+			assert.Equal(t, "a", candidates[0].Person.GetName())
+			assert.InDelta(t, 0.05, candidates[0].Distance, 1e-6)
+			assert.True(t, candidates[0].Matched())
+			assert.Equal(t, "c", candidates[1].Person.GetName())
+			assert.InDelta(t, 0.25, candidates[1].Distance, 1e-6)
+		}
+	})
+
+	t.Run("verdicts", func(t *testing.T) {
+		candidates, err := people.MatchTopK([]float32{0.05, 0, 0}, 5)
+		if assert.NoError(t, err) && assert.Len(t, candidates, 3) {
+			b := candidates[2]
+			assert.Equal(t, "b", b.Person.GetName())
+			assert.False(t, b.Matched())
+			assert.False(t, b.Collision())
+			assert.Equal(t, TooFarMatchErr, b.Err.(Error).Code)
+		}
+	})
+
+	t.Run("same as match", func(t *testing.T) {
+		query := []float32{0.9, 1, 1}
+		person, dist, err := people.Match(query)
+		candidates, topErr := people.MatchTopK(query, 1)
+		if assert.NoError(t, topErr) && assert.Len(t, candidates, 1) {
+			assert.Equal(t, person, candidates[0].Person)
+			assert.Equal(t, dist, candidates[0].Distance)
+			assert.Equal(t, err, candidates[0].Err)
+		}
+	})
+
+	t.Run("dimension mismatch", func(t *testing.T) {
+		_, err := people.MatchTopK([]float32{0.05, 0}, 2)
+		if assert.Error(t, err) {
+			assert.Equal(t, DimensionMismatchErr, err.(Error).Code)
+		}
+	})
+
+	t.Run("no people", func(t *testing.T) {
+		_, err := new(People).MatchTopK([]float32{0.05, 0, 0}, 2)
+		if assert.Error(t, err) {
+			assert.Equal(t, NothingMatchErr, err.(Error).Code)
+		}
+	})
+
+	t.Run("cosine zero query", func(t *testing.T) {
+		people := testPeople()
+		people.Metric = Metric_COSINE
+		persons, dists := people.NearestK([]float32{0, 0, 0}, 5)
+		assert.Len(t, persons, 2)
+		assert.Equal(t, []float64{1, 1}, dists)
+	})
+}
+
+func TestPerson_Match(t *testing.T) {
+	people := testPeople()
+	people.Setup()
+	person := people.List[0]
+
+	center, radius, _ := person.CalcCenter()
+	assert.Equal(t, person.Center, center)
+	assert.Equal(t, person.Radius, radius)
+
+	matched, dist := person.Match([]float32{0.05, 0, 0})
+	assert.True(t, matched)
+	expectMatched, expectDist := person.MatchMetric([]float32{0.05, 0, 0}, Metric_EUCLIDEAN, MatchDist)
+	assert.Equal(t, expectMatched, matched)
+	assert.Equal(t, expectDist, dist)
+
+	matched, _ = person.Match([]float32{1, 1, 1})
+	assert.False(t, matched)
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"math"
+)
+
+// Face proportions of the five point reference template, relative to the distance between eyes.
+const (
+	// poseNoseDrop vertical distance from the eyes midpoint to the nose tip
+	poseNoseDrop = 0.571
+	// poseMouthDrop vertical distance from the eyes midpoint to the mouth center
+	poseMouthDrop = 1.159
+	// poseNoseDepth depth of the nose tip in front of the eyes plane
+	poseNoseDepth = 0.5
+)
+
+// Pose represents an estimated head pose in degrees
+type Pose struct {
+	// Yaw positive when the face turns towards the right side of the image
+	Yaw float64 `json:"yaw"`
+	// Pitch positive when the face looks down
+	Pitch float64 `json:"pitch"`
+	// Roll positive when the face is tilted clockwise in the image
+	Roll float64 `json:"roll"`
+	// Confidence in [0, 1], 0 if both eyes are missing, higher with the nose and mouth found
+	Confidence float64 `json:"confidence"`
+}
+
+// Pose estimates the head pose from the eyes, nose and mouth landmarks.
+// Roll only needs both eyes, yaw and pitch also need the nose.
+func (f *Face) Pose() Pose {
+	left, right, ok := f.EyesPoints()
+	if !ok {
+		return Pose{}
+	}
+	ex, ey := float64(left.X+right.X)/2, float64(left.Y+right.Y)/2
+	dx, dy := float64(right.X-left.X), float64(right.Y-left.Y)
+	dist := math.Hypot(dx, dy)
+	roll := math.Atan2(dy, dx)
+
+	// upright returns a point relative to the eyes midpoint with the roll removed
+	sin, cos := math.Sincos(-roll)
+	upright := func(p Area) (float64, float64) {
+		px, py := float64(p.Col)-ex, float64(p.Row)-ey
+		return cos*px - sin*py, sin*px + cos*py
+	}
+
+	pose := Pose{
+		Roll:       roll * 180 / math.Pi,
+		Confidence: 0.3,
+	}
+	landmarks := NewLandmarks(f.Eyes, f.Landmarks)
+	mouth, hasMouth := landmarks.mouthCenter()
+	if hasMouth {
+		pose.Confidence += 0.3
+	}
+	if landmarks.NoseTip == nil {
+		return pose
+	}
+	nose := *landmarks.NoseTip
+	pose.Confidence += 0.4
+
+	// The nose tip stands out of the face plane, so it moves sideways when the face turns
+	// and moves towards the mouth when the face looks down.
+	nx, ny := upright(nose)
+	pose.Yaw = math.Atan(nx/(poseNoseDepth*dist)) * 180 / math.Pi
+	pose.Pitch = math.Atan((ny/dist-poseNoseDrop)/poseNoseDepth) * 180 / math.Pi
+	if hasMouth {
+		// The mouth to eyes distance removes the foreshortening of the nose drop.
+		if _, my := upright(mouth); my > 0 {
+			pose.Pitch = math.Atan((ny/my*poseMouthDrop-poseNoseDrop)/poseNoseDepth) * 180 / math.Pi
+		}
+	}
+	return pose
+}
+
+// HasPose tests if both eyes were found, which the head pose is estimated from
+func (f *Face) HasPose() bool {
+	_, _, ok := f.EyesPoints()
+	return ok
+}
+
+// mouthCenter returns the midpoint of mouth corners, or of lips if corners are missing
+func (l Landmarks) mouthCenter() (Area, bool) {
+	a, b := l.MouthLeft, l.MouthRight
+	if a == nil || b == nil {
+		a, b = l.UpperLip, l.LowerLip
+	}
+	if a == nil || b == nil {
+		return Area{}, false
+	}
+	return NewArea("mouth", (a.Row+b.Row)/2, (a.Col+b.Col)/2, 0), true
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+// makeBatchTensorFromImages stacks normalized images into one [N,height,width,3] tensor
+func makeBatchTensorFromImages(imgs []image.Image, imageWidth int, imageHeight int, normalization Normalization) (*tf.Tensor, error) {
+	if imageWidth <= 0 || imageHeight <= 0 {
+		return nil, NewError(ImageToTensorSizeErr, "image width and height must be > 0")
+	}
+	batch := make([][][][]float32, 0, len(imgs))
+	for _, img := range imgs {
+		pixels, err := imageToPixels(img, imageWidth, imageHeight)
+		if err != nil {
+			return nil, err
+		}
+		if normalization == ScaleNormalization {
+			batch = append(batch, scaleImage(pixels))
+		} else {
+			batch = append(batch, preWhitenImage(pixels))
+		}
+	}
+	tensor, err := tf.NewTensor(batch)
+	if err != nil {
+		// the shape of the batch fails every crop alike
+		return nil, NewError(InferenceFailedErr, fmt.Sprintf("inference failed, %v", err))
+	}
+	return tensor, nil
+}
+
+// imageToPixels resizes img to imageWidth x imageHeight and returns its RGB values in [0, 255] as [height][width][3]
+func imageToPixels(img image.Image, imageWidth int, imageHeight int) ([][][]float32, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, NewError(ImageToTensorSizeErr, "image is empty")
+	}
+	var src *image.NRGBA
+	if bounds.Dx() != imageWidth || bounds.Dy() != imageHeight {
+		src = imaging.Resize(img, imageWidth, imageHeight, imaging.Linear)
+	} else {
+		src = imaging.Clone(img)
+	}
+
+	// One backing array for all the values to avoid small allocations per pixel.
+	values := make([]float32, imageWidth*imageHeight*3)
+	cols := make([][]float32, imageWidth*imageHeight)
+	pixels := make([][][]float32, imageHeight)
+	for y := 0; y < imageHeight; y++ {
+		pixels[y] = cols[y*imageWidth : (y+1)*imageWidth]
+		row := src.Pix[y*src.Stride : y*src.Stride+imageWidth*4]
+		for x := 0; x < imageWidth; x++ {
+			v := values[(y*imageWidth+x)*3 : (y*imageWidth+x)*3+3]
+			v[0] = float32(row[x*4])
+			v[1] = float32(row[x*4+1])
+			v[2] = float32(row[x*4+2])
+			pixels[y][x] = v
+		}
+	}
+	return pixels, nil
+}
+
+// preWhitenImage normalizes pixels in place to zero mean and unit variance
+func preWhitenImage(pixels [][][]float32) [][][]float32 {
+	mean, std := meanStd(pixels)
+	scale := float32(1.0) / std
+	for _, x := range pixels {
+		for _, y := range x {
+			for k, z := range y {
+				y[k] = (z - mean) * scale
+			}
+		}
+	}
+	return pixels
+}
+
+// scaleImage scales pixels in place to [-1, 1]
+func scaleImage(pixels [][][]float32) [][][]float32 {
+	for _, x := range pixels {
+		for _, y := range x {
+			for k, z := range y {
+				y[k] = (z - 127.5) / 127.5
+			}
+		}
+	}
+	return pixels
+}
+
+func meanStd(img [][][]float32) (mean float32, std float32) {
+	count := len(img) * len(img[0]) * len(img[0][0])
+	for _, x := range img {
+		for _, y := range x {
+			for _, z := range y {
+				mean += z
+			}
+		}
+	}
+	mean /= float32(count)
+
+	for _, x := range img {
+		for _, y := range x {
+			for _, z := range y {
+				std += (z - mean) * (z - mean)
+			}
+		}
+	}
+
+	xstd := math.Sqrt(float64(std) / float64(count-1))
+	minstd := 1.0 / math.Sqrt(float64(count))
+	if xstd < minstd {
+		xstd = minstd
+	}
+
+	std = float32(xstd)
+	return
+}
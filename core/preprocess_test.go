@@ -0,0 +1,97 @@
+package core
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+	"github.com/tensorflow/tensorflow/tensorflow/go/op"
+
+	"github.com/bububa/facenet/imageutil"
+)
+
+func testThumb(t testing.TB) image.Image {
+	img := loadTestImage(t, "../testdata/2.jpg")
+	return imageutil.Resample(img, CropSize.Width, CropSize.Height, imageutil.ResampleResize)
+}
+
+func TestPreWhitenImage(t *testing.T) {
+	pixels, err := imageToPixels(testThumb(t), CropSize.Width, CropSize.Height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pixels = preWhitenImage(pixels)
+	assert.Len(t, pixels, CropSize.Height)
+	assert.Len(t, pixels[0], CropSize.Width)
+
+	mean, std := meanStd(pixels)
+	assert.InDelta(t, 0, mean, 1e-3)
+	assert.InDelta(t, 1, std, 1e-3)
+}
+
+func TestScaleImage(t *testing.T) {
+	pixels := scaleImage([][][]float32{{{0, 127.5, 255}}})
+	assert.Equal(t, []float32{-1, 0, 1}, pixels[0][0])
+}
+
+func TestImageToPixels_Empty(t *testing.T) {
+	_, err := imageToPixels(image.NewNRGBA(image.Rect(0, 0, 0, 0)), CropSize.Width, CropSize.Height)
+	if assert.Error(t, err) {
+		assert.Equal(t, ImageToTensorSizeErr, err.(Error).Code)
+	}
+}
+
+// graphTensorFromImage is the former TensorFlow graph based preprocessing, kept for benchmark comparison
+func graphTensorFromImage(img image.Image, imageWidth int, imageHeight int) (*tf.Tensor, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	tensor, err := tf.NewTensor(buf.String())
+	if err != nil {
+		return nil, err
+	}
+	s := op.NewScope()
+	input := op.Placeholder(s, tf.String)
+	decode := op.DecodeJpeg(s, input, op.DecodeJpegChannels(3))
+	output := op.ResizeBilinear(s,
+		op.ExpandDims(s, op.Cast(s, decode, tf.Float), op.Const(s.SubScope("make_batch"), int32(0))),
+		op.Const(s.SubScope("size"), []int32{int32(imageHeight), int32(imageWidth)}),
+	)
+	graph, err := s.Finalize()
+	if err != nil {
+		return nil, err
+	}
+	session, err := tf.NewSession(graph, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	out, err := session.Run(map[tf.Output]*tf.Tensor{input: tensor}, []tf.Output{output}, nil)
+	if err != nil {
+		return nil, err
+	}
+	pixels := out[0].Value().([][][][]float32)
+	return tf.NewTensor([][][][]float32{preWhitenImage(pixels[0])})
+}
+
+func BenchmarkMakeTensorFromImage(b *testing.B) {
+	thumb := testThumb(b)
+	b.Run("go", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := makeBatchTensorFromImages([]image.Image{thumb}, CropSize.Width, CropSize.Height, PrewhitenNormalization); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("graph", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := graphTensorFromImage(thumb, CropSize.Width, CropSize.Height); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
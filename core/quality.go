@@ -0,0 +1,149 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Quality factors reach 1 at these values.
+const (
+	// sharpnessNorm Laplacian variance of a face crop considered fully sharp
+	sharpnessNorm = 200.0
+	// contrastNorm standard deviation of gray levels of a face crop considered fully contrasted
+	contrastNorm = 48.0
+	// scoreNorm detection score considered fully confident
+	scoreNorm = 50.0
+)
+
+// Quality represents the quality of a detected face, each factor is in [0, 1], higher is better
+type Quality struct {
+	// Sharpness Laplacian variance of the face crop
+	Sharpness float64 `json:"sharpness"`
+	// Brightness distance of the mean gray level to mid gray
+	Brightness float64 `json:"brightness"`
+	// Contrast standard deviation of gray levels
+	Contrast float64 `json:"contrast"`
+	// Size face size in source image pixels relative to CropSize
+	Size float64 `json:"size"`
+	// Score detection score
+	Score float64 `json:"score"`
+	// Landmarks completeness of eyes, nose and mouth landmarks
+	Landmarks float64 `json:"landmarks"`
+	// Overall weighted mean of all factors
+	Overall float64 `json:"overall"`
+}
+
+// NewQuality scores a face with its crop, as made by the detection pipeline before embedding
+func NewQuality(face Face, thumb image.Image) Quality {
+	mean, std, lapVar := grayStats(thumb)
+	size := float64(face.Size())
+	if face.Transform.Scale > 0 {
+		size *= face.Transform.Scale
+	}
+	q := Quality{
+		Sharpness:  normalize(lapVar, sharpnessNorm),
+		Brightness: 1 - math.Abs(mean-127.5)/127.5,
+		Contrast:   normalize(std, contrastNorm),
+		Size:       normalize(size, float64(CropSize.Width)),
+		Score:      normalize(float64(face.Score), scoreNorm),
+		Landmarks:  face.Pose().Confidence,
+	}
+	// Blur is the main reason of bad embeddings.
+	q.Overall = 0.3*q.Sharpness +
+		0.15*q.Brightness +
+		0.15*q.Contrast +
+		0.15*q.Size +
+		0.15*q.Score +
+		0.1*q.Landmarks
+	return q
+}
+
+// normalize maps v to [0, 1], norm and above being 1
+func normalize(v float64, norm float64) float64 {
+	if norm <= 0 || v <= 0 {
+		return 0
+	}
+	return math.Min(v/norm, 1)
+}
+
+// grayStats returns the mean and standard deviation of gray levels and the variance of their Laplacian
+func grayStats(img image.Image) (mean float64, std float64, lapVar float64) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return 0, 0, 0
+	}
+	gray := grayLevels(img)
+	var sum, sqSum float64
+	for _, v := range gray {
+		sum += v
+		sqSum += v * v
+	}
+	n := float64(w * h)
+	mean = sum / n
+	std = math.Sqrt(math.Max(sqSum/n-mean*mean, 0))
+
+	if w < 3 || h < 3 {
+		return mean, std, 0
+	}
+	var lapSum, lapSqSum float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			lap := gray[i-w] + gray[i+w] + gray[i-1] + gray[i+1] - 4*gray[i]
+			lapSum += lap
+			lapSqSum += lap * lap
+		}
+	}
+	n = float64((w - 2) * (h - 2))
+	lapMean := lapSum / n
+	lapVar = math.Max(lapSqSum/n-lapMean*lapMean, 0)
+	return mean, std, lapVar
+}
+
+// grayLevels returns the gray levels of img row by row, pixels of the usual crop types are read without color conversions
+func grayLevels(img image.Image) []float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	gray := make([]float64, 0, w*h)
+	switch src := img.(type) {
+	case *image.NRGBA:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i := src.PixOffset(x, y)
+				if p := src.Pix[i : i+4 : i+4]; p[3] == 0xff {
+					gray = append(gray, float64(grayLevel(uint32(p[0])*0x101, uint32(p[1])*0x101, uint32(p[2])*0x101)))
+					continue
+				}
+				gray = append(gray, float64(color.GrayModel.Convert(src.At(x, y)).(color.Gray).Y))
+			}
+		}
+	case *image.YCbCr:
+		// luma is the gray level
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				gray = append(gray, float64(src.Y[src.YOffset(x, y)]))
+			}
+		}
+	case *image.Gray:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			i := src.PixOffset(bounds.Min.X, y)
+			for _, v := range src.Pix[i : i+w] {
+				gray = append(gray, float64(v))
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				gray = append(gray, float64(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y))
+			}
+		}
+	}
+	return gray
+}
+
+// grayLevel converts 16-bit RGB values to a gray level as color.GrayModel does
+func grayLevel(r, g, b uint32) uint8 {
+	return uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewQuality(t *testing.T) {
+	newImage := func(fill func(x, y int) uint8) image.Image {
+		img := image.NewGray(image.Rect(0, 0, CropSize.Width, CropSize.Height))
+		for y := 0; y < CropSize.Height; y++ {
+			for x := 0; x < CropSize.Width; x++ {
+				img.SetGray(x, y, color.Gray{Y: fill(x, y)})
+			}
+		}
+		return img
+	}
+	face := Face{
+		Score: 100,
+		Area:  NewArea("face", 200, 200, CropSize.Width),
+		Eyes: Areas{
+			NewArea("eye_l", 517, 383, 40),
+			NewArea("eye_r", 515, 735, 40),
+		},
+		Landmarks: Areas{
+			NewArea("nose", 717, 560, 40),
+			NewArea("mouth_l", 924, 415, 40),
+			NewArea("mouth_r", 922, 707, 40),
+		},
+	}
+
+	t.Run("sharp", func(t *testing.T) {
+		q := NewQuality(face, newImage(func(x, y int) uint8 {
+			if (x/2+y/2)%2 == 0 {
+				return 32
+			}
+			return 224
+		}))
+		assert.Equal(t, 1.0, q.Sharpness)
+		assert.Equal(t, 1.0, q.Contrast)
+		assert.InDelta(t, 1.0, q.Brightness, 0.01)
+		assert.Equal(t, 1.0, q.Size)
+		assert.Equal(t, 1.0, q.Score)
+		assert.Equal(t, 1.0, q.Landmarks)
+		assert.InDelta(t, 1.0, q.Overall, 0.01)
+	})
+
+	t.Run("flat", func(t *testing.T) {
+		q := NewQuality(face, newImage(func(x, y int) uint8 {
+			return 128
+		}))
+		assert.Equal(t, 0.0, q.Sharpness)
+		assert.Equal(t, 0.0, q.Contrast)
+		assert.Less(t, q.Overall, 0.6)
+	})
+
+	t.Run("dark", func(t *testing.T) {
+		q := NewQuality(face, newImage(func(x, y int) uint8 {
+			return 0
+		}))
+		assert.Equal(t, 0.0, q.Brightness)
+	})
+
+	t.Run("tiny", func(t *testing.T) {
+		f := face
+		f.Area.Scale = CropSize.Width / 4
+		f.Score = 10
+		f.Landmarks = nil
+		q := NewQuality(f, newImage(func(x, y int) uint8 {
+			return 128
+		}))
+		assert.Equal(t, 0.25, q.Size)
+		assert.Equal(t, 0.2, q.Score)
+		assert.Equal(t, 0.3, q.Landmarks)
+	})
+
+	t.Run("downscaled", func(t *testing.T) {
+		f := face
+		f.Area.Scale = CropSize.Width / 4
+		f.Transform = Transform{Scale: 2}
+		q := NewQuality(f, newImage(func(x, y int) uint8 {
+			return 128
+		}))
+		assert.Equal(t, 0.5, q.Size)
+	})
+}
+
+func TestGrayLevels(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(3, 5, 19, 17))
+	for y := rgba.Rect.Min.Y; y < rgba.Rect.Max.Y; y++ {
+		for x := rgba.Rect.Min.X; x < rgba.Rect.Max.X; x++ {
+			rgba.Set(x, y, color.RGBA{R: uint8(x * 13), G: uint8(y * 7), B: uint8(x * y), A: 0xff})
+		}
+	}
+	expected := grayLevels(rgba)
+	assert.Len(t, expected, rgba.Rect.Dx()*rgba.Rect.Dy())
+
+	nrgba := image.NewNRGBA(rgba.Rect)
+	gray := image.NewGray(rgba.Rect)
+	ycbcr := image.NewYCbCr(rgba.Rect, image.YCbCrSubsampleRatio444)
+	for y := rgba.Rect.Min.Y; y < rgba.Rect.Max.Y; y++ {
+		for x := rgba.Rect.Min.X; x < rgba.Rect.Max.X; x++ {
+			c := rgba.RGBAAt(x, y)
+			nrgba.Set(x, y, c)
+			gray.Set(x, y, c)
+			yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			ycbcr.Y[ycbcr.YOffset(x, y)] = yy
+			ycbcr.Cb[ycbcr.COffset(x, y)] = cb
+			ycbcr.Cr[ycbcr.COffset(x, y)] = cr
+		}
+	}
+	assert.Equal(t, expected, grayLevels(nrgba))
+	assert.Equal(t, expected, grayLevels(gray))
+	assert.InDeltaSlice(t, expected, grayLevels(ycbcr), 1)
+}
+
+func TestDetectOptions_CheckQuality(t *testing.T) {
+	face := Face{Quality: &Quality{Overall: 0.4}}
+	assert.NoError(t, newDetectOptions().checkQuality(face))
+	assert.NoError(t, newDetectOptions(WithMinQuality(0.4)).checkQuality(face))
+	err := newDetectOptions(WithMinQuality(0.5)).checkQuality(face)
+	if assert.Error(t, err) {
+		assert.Equal(t, LowQualityErr, err.(Error).Code)
+	}
+	assert.Error(t, newDetectOptions(WithMinQuality(0.5)).checkQuality(Face{}))
+	assert.True(t, newDetectOptions(WithMinQuality(0.5)).findLandmarks())
+
+	assert.False(t, newDetectOptions().scoreQuality())
+	assert.True(t, newDetectOptions(WithQuality(true)).scoreQuality())
+	assert.True(t, newDetectOptions(WithMinQuality(0.5)).scoreQuality())
+}
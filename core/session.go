@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+
+	tf "github.com/tensorflow/tensorflow/tensorflow/go"
+)
+
+// tfSession holds a TensorFlow graph and its session, loaded on first use and loaded again after Close
+type tfSession struct {
+	graph   *tf.Graph
+	session *tf.Session
+	mutex   sync.RWMutex
+}
+
+// ModelLoaded tests if the TensorFlow model is loaded.
+func (s *tfSession) ModelLoaded() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.loaded()
+}
+
+// loaded tests if the session is open, the caller holds the mutex
+func (s *tfSession) loaded() bool {
+	return s.session != nil
+}
+
+// load opens the session with open if not loaded yet, the graph must hold all operations
+func (s *tfSession) load(open func() (*tf.Graph, *tf.Session, error), operations ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.loaded() {
+		return nil
+	}
+
+	graph, session, err := open()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range operations {
+		if name != "" && graph.Operation(name) == nil {
+			session.Close()
+			return NewError(InferenceFailedErr, fmt.Sprintf("operation %s not found in model", name))
+		}
+	}
+
+	s.graph = graph
+	s.session = session
+
+	return nil
+}
+
+// acquire takes the read lock which keeps the session open until release,
+// the model is loaded again with load if closed before the lock was taken.
+func (s *tfSession) acquire(load func() error) error {
+	s.mutex.RLock()
+	for !s.loaded() {
+		s.mutex.RUnlock()
+		if err := load(); err != nil {
+			return err
+		}
+		s.mutex.RLock()
+	}
+	return nil
+}
+
+// release releases the read lock taken by acquire
+func (s *tfSession) release() {
+	s.mutex.RUnlock()
+}
+
+// Close releases the TensorFlow session, waiting for in-flight inferences. The model is loaded again on next use.
+func (s *tfSession) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.session == nil {
+		return nil
+	}
+	err := s.session.Close()
+	s.graph = nil
+	s.session = nil
+	return err
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"image"
+	"sort"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/bububa/facenet/imageutil"
+)
+
+// TiledDetector detects faces in overlapping tiles of an image at native resolution,
+// so that small faces of high resolution images are not lost by downscaling.
+// Faces larger than the tile overlap are found by an extra pass over the image downscaled to the tile size.
+type TiledDetector struct {
+	detector Detector
+	tileSize int
+	overlap  int
+}
+
+// NewTiledDetector returns a TiledDetector running detector on tiles of tileSize pixels overlapping by overlap pixels.
+// The pigo detector is used if detector is nil, tileSize defaults to MaxImageSize and overlap to a third of tileSize.
+func NewTiledDetector(detector Detector, tileSize int, overlap int) *TiledDetector {
+	if detector == nil {
+		detector = NewExtractor(20)
+	}
+	if tileSize <= 0 {
+		tileSize = MaxImageSize
+	}
+	if overlap <= 0 || overlap >= tileSize {
+		overlap = tileSize / 3
+	}
+	return &TiledDetector{
+		detector: detector,
+		tileSize: tileSize,
+		overlap:  overlap,
+	}
+}
+
+// Detect implements Detector interface, faces are in img coordinates
+func (d *TiledDetector) Detect(ctx context.Context, img image.Image, findLandmarks bool, minSize int) (Faces, error) {
+	bounds := img.Bounds()
+	rows, cols := bounds.Dy(), bounds.Dx()
+	if rows <= d.tileSize && cols <= d.tileSize {
+		return d.detect(ctx, img, findLandmarks, minSize)
+	}
+
+	var found Faces
+
+	// Large faces may be cut by any tile.
+	small := imageutil.NormalizeImage(img, d.tileSize)
+	scale := float64(cols) / float64(small.Bounds().Dx())
+	faces, err := d.detect(ctx, small, findLandmarks, minSize)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range faces {
+		found = append(found, transformFace(f, scale, 0, 0, rows, cols))
+	}
+
+	for _, y := range tileStarts(rows, d.tileSize, d.overlap) {
+		for _, x := range tileStarts(cols, d.tileSize, d.overlap) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			rect := image.Rect(x, y, x+d.tileSize, y+d.tileSize).Add(bounds.Min).Intersect(bounds)
+			faces, err := d.detect(ctx, imaging.Crop(img, rect), findLandmarks, minSize)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range faces {
+				found = append(found, transformFace(f, 1, y, x, rows, cols))
+			}
+		}
+	}
+
+	return mergeFaces(found), nil
+}
+
+// detect runs the wrapped detector, no face found is not an error
+func (d *TiledDetector) detect(ctx context.Context, img image.Image, findLandmarks bool, minSize int) (Faces, error) {
+	faces, err := d.detector.Detect(ctx, img, findLandmarks, minSize)
+	var e Error
+	if errors.As(err, &e) && (e.Code == NoFaceErr || e.Code == ExtractImageSizeTooSmallErr) {
+		return nil, nil
+	}
+	return faces, err
+}
+
+// tileStarts returns the offsets of tiles covering size, the last tile ends at size
+func tileStarts(size int, tileSize int, overlap int) []int {
+	if size <= tileSize {
+		return []int{0}
+	}
+	step := tileSize - overlap
+	var starts []int
+	for start := 0; start+tileSize < size; start += step {
+		starts = append(starts, start)
+	}
+	return append(starts, size-tileSize)
+}
+
+// mergeFaces drops faces overlapping larger ones, e.g. faces cut by tile borders
+func mergeFaces(faces Faces) Faces {
+	sort.SliceStable(faces, func(i, j int) bool {
+		return faces[i].Area.Scale > faces[j].Area.Scale
+	})
+	merged := NewFaces(len(faces))
+	for _, f := range faces {
+		if !merged.Contains(f) {
+			merged.Append(f)
+		}
+	}
+	return merged
+}
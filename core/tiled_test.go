@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// dotDetector finds a face of 40px around each pure red pixel
+type dotDetector struct {
+	calls int
+}
+
+func (d *dotDetector) Detect(ctx context.Context, img image.Image, findLandmarks bool, minSize int) (Faces, error) {
+	d.calls++
+	bounds := img.Bounds()
+	var faces Faces
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); r == 0xffff && g == 0 && b == 0 {
+				faces = append(faces, Face{
+					Rows:  bounds.Dy(),
+					Cols:  bounds.Dx(),
+					Score: 10,
+					Area:  NewArea("face", y-bounds.Min.Y, x-bounds.Min.X, 40),
+					Eyes:  Areas{NewArea("eye_l", y-bounds.Min.Y-5, x-bounds.Min.X-8, 5)},
+				})
+			}
+		}
+	}
+	if len(faces) == 0 {
+		return nil, NewError(NoFaceErr, "no face detected")
+	}
+	return faces, nil
+}
+
+// closerDetector records Close calls
+type closerDetector struct {
+	dotDetector
+	closed int
+}
+
+func (d *closerDetector) Close() error {
+	d.closed++
+	return nil
+}
+
+func TestCloseDetector(t *testing.T) {
+	detector := new(closerDetector)
+	assert.NoError(t, CloseDetector(WithAlignment(true), WithDetector(detector)))
+	assert.Equal(t, 1, detector.closed)
+	assert.NoError(t, CloseDetector(WithDetector(new(dotDetector))))
+	assert.NoError(t, CloseDetector())
+}
+
+func TestTileStarts(t *testing.T) {
+	assert.Equal(t, []int{0}, tileStarts(500, 640, 200))
+	assert.Equal(t, []int{0}, tileStarts(640, 640, 200))
+	assert.Equal(t, []int{0, 440, 560}, tileStarts(1200, 640, 200))
+}
+
+func TestTiledDetector(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2000, 1500))
+	red := color.RGBA{R: 255, A: 255}
+	// the second dot is in the overlap of two tiles
+	img.Set(100, 100, red)
+	img.Set(500, 300, red)
+	img.Set(1900, 1400, red)
+
+	detector := &dotDetector{}
+	faces, err := NewTiledDetector(detector, 640, 200).Detect(context.Background(), img, true, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1 downscaled pass and 5x3 tiles
+	assert.Equal(t, 16, detector.calls)
+	if assert.Len(t, faces, 3) {
+		found := make(map[image.Point]bool, len(faces))
+		for _, f := range faces {
+			assert.Equal(t, 1500, f.Rows)
+			assert.Equal(t, 2000, f.Cols)
+			found[image.Pt(f.Area.Col, f.Area.Row)] = true
+			if assert.Len(t, f.Eyes, 1) {
+				assert.Equal(t, f.Area.Col-8, f.Eyes[0].Col)
+				assert.Equal(t, f.Points.LeftEye, &f.Eyes[0])
+			}
+		}
+		assert.Equal(t, map[image.Point]bool{
+			image.Pt(100, 100):   true,
+			image.Pt(500, 300):   true,
+			image.Pt(1900, 1400): true,
+		}, found)
+	}
+
+	t.Run("small image", func(t *testing.T) {
+		detector := &dotDetector{}
+		small := image.NewRGBA(image.Rect(0, 0, 400, 300))
+		small.Set(200, 150, red)
+		faces, err := NewTiledDetector(detector, 640, 200).Detect(context.Background(), small, false, 20)
+		assert.NoError(t, err)
+		assert.Len(t, faces, 1)
+		assert.Equal(t, 1, detector.calls)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := NewTiledDetector(&dotDetector{}, 640, 200).Detect(ctx, img, false, 20)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestTransformFace(t *testing.T) {
+	f := Face{
+		Rows: 100,
+		Cols: 200,
+		Area: NewArea("face", 50, 60, 20),
+		Eyes: Areas{NewArea("eye_l", 45, 55, 4), NewArea("eye_r", 45, 65, 4)},
+	}
+	scaled := transformFace(f, 2, 10, 20, 300, 600)
+	assert.Equal(t, NewArea("face", 110, 140, 40), scaled.Area)
+	assert.Equal(t, NewArea("eye_l", 100, 130, 8), scaled.Eyes[0])
+	assert.Equal(t, 300, scaled.Rows)
+	assert.Equal(t, 600, scaled.Cols)
+	// the source face is untouched
+	assert.Equal(t, 45, f.Eyes[0].Row)
+}
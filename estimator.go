@@ -1,8 +1,10 @@
 package facenet
 
 import (
+	"context"
 	"errors"
 	"image"
+	"io"
 	"sync"
 
 	"github.com/llgcode/draw2d"
@@ -13,10 +15,11 @@ import (
 
 // Estimator represents facenet estimator
 type Estimator struct {
-	model *core.Net
-	db    *Storage
-	font  *imageutil.Font
-	lock  *sync.RWMutex
+	model      core.Embedder
+	db         *Storage
+	font       *imageutil.Font
+	detectOpts []core.DetectOption
+	lock       *sync.RWMutex
 }
 
 // New init a new facenet Estimator
@@ -33,10 +36,52 @@ func New(opts ...Option) (*Estimator, error) {
 }
 
 // SetModel set face net model
-func (ins *Estimator) SetModel(net *core.Net) {
+func (ins *Estimator) SetModel(model core.Embedder) {
 	ins.lock.Lock()
 	defer ins.lock.Unlock()
-	ins.model = net
+	ins.model = model
+}
+
+// ReloadModel loads and warms up the net model at modelPath, then swaps it in.
+// In-flight multithread safe calls finish on the previous model, which is closed afterwards.
+func (ins *Estimator) ReloadModel(modelPath string, opts ...core.NetOption) error {
+	model := core.NewNet(modelPath, opts...)
+	if err := model.Warmup(); err != nil {
+		model.Close()
+		return err
+	}
+	ins.lock.Lock()
+	old := ins.model
+	ins.model = model
+	ins.lock.Unlock()
+	if closer, ok := old.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Warmup runs a dummy inference if the model supports it, so that the first detection is not slow
+func (ins *Estimator) Warmup() error {
+	ins.lock.RLock()
+	defer ins.lock.RUnlock()
+	if warmer, ok := ins.model.(interface{ Warmup() error }); ok {
+		return warmer.Warmup()
+	}
+	return nil
+}
+
+// Close releases the model and detector resources
+func (ins *Estimator) Close() error {
+	ins.lock.Lock()
+	defer ins.lock.Unlock()
+	var err error
+	if closer, ok := ins.model.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if detectorErr := core.CloseDetector(ins.detectOpts...); err == nil {
+		err = detectorErr
+	}
+	return err
 }
 
 // SetDB set db
@@ -56,6 +101,16 @@ func (ins *Estimator) LoadDB(fname string) error {
 	return ins.db.Load(fname)
 }
 
+// SetMetric set people distance metric, centers and collisions are recalculated
+func (ins *Estimator) SetMetric(metric core.Metric) {
+	ins.lock.Lock()
+	defer ins.lock.Unlock()
+	if ins.db == nil {
+		ins.db = NewStorage(nil, nil)
+	}
+	ins.db.SetMetric(metric)
+}
+
 // SaveDB save db file
 func (ins *Estimator) SaveDB(fname string) error {
 	ins.lock.RLock()
@@ -179,6 +234,21 @@ func (ins *Estimator) MatchSafe(embedding []float32) (*core.Person, float64, err
 	return ins.Match(embedding)
 }
 
+// MatchTopK returns the k persons nearest to embedding, with match verdicts and classifier scores
+func (ins *Estimator) MatchTopK(embedding []float32, k int) ([]core.Candidate, error) {
+	if ins.db == nil {
+		return nil, errors.New("no db inited")
+	}
+	return ins.db.MatchTopK(embedding, k)
+}
+
+// MatchTopKSafe returns the k persons nearest to embedding, with match verdicts and classifier scores (multithread safe)
+func (ins *Estimator) MatchTopKSafe(embedding []float32, k int) ([]core.Candidate, error) {
+	ins.lock.RLock()
+	defer ins.lock.RUnlock()
+	return ins.MatchTopK(embedding, k)
+}
+
 // Predict returns embedding predicted results
 func (ins *Estimator) Predict(embedding []float32) ([]*core.Person, []float64, error) {
 	if ins.db == nil {
@@ -194,12 +264,22 @@ func (ins *Estimator) PredictSafe(embedding []float32) ([]*core.Person, []float6
 	return ins.Predict(embedding)
 }
 
-// ExtractFace extract face for a person from image
+// ExtractFace extract face for a person from image, faces below the minimum quality set by core.WithMinQuality are rejected
 func (ins *Estimator) ExtractFace(person *core.Person, img image.Image, minSize int) (*core.FaceMarker, error) {
+	return ins.ExtractFaceContext(context.Background(), person, img, minSize)
+}
+
+// ExtractFaceSafe extract face for a person from image (multithread safe)
+func (ins *Estimator) ExtractFaceSafe(person *core.Person, img image.Image, minSize int) (*core.FaceMarker, error) {
+	return ins.ExtractFaceContextSafe(context.Background(), person, img, minSize)
+}
+
+// ExtractFaceContext extract face for a person from image, returns ctx.Err() once ctx is done
+func (ins *Estimator) ExtractFaceContext(ctx context.Context, person *core.Person, img image.Image, minSize int) (*core.FaceMarker, error) {
 	if ins.model == nil {
 		return nil, errors.New("model not inited")
 	}
-	face, err := ins.model.DetectSingle(img, minSize)
+	face, err := core.DetectTrainingFaceContext(ctx, ins.model, img, minSize, ins.detectOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -209,24 +289,40 @@ func (ins *Estimator) ExtractFace(person *core.Person, img image.Image, minSize
 	return core.NewFaceMarker(face, person.GetName(), 1), nil
 }
 
-// ExtractFaceSafe extract face for a person from image (multithread safe)
-func (ins *Estimator) ExtractFaceSafe(person *core.Person, img image.Image, minSize int) (*core.FaceMarker, error) {
+// ExtractFaceContextSafe extract face for a person from image, returns ctx.Err() once ctx is done (multithread safe)
+func (ins *Estimator) ExtractFaceContextSafe(ctx context.Context, person *core.Person, img image.Image, minSize int) (*core.FaceMarker, error) {
 	ins.lock.RLock()
 	defer ins.lock.RUnlock()
-	return ins.ExtractFace(person, img, minSize)
+	return ins.ExtractFaceContext(ctx, person, img, minSize)
 }
 
 // DetectFaces detect face markers from image
 func (ins *Estimator) DetectFaces(img image.Image, minSize int) (*core.FaceMarkers, error) {
+	return ins.DetectFacesContext(context.Background(), img, minSize)
+}
+
+// DetectFacesSafe detect face markers from image (multithread safe)
+func (ins *Estimator) DetectFacesSafe(img image.Image, minSize int) (*core.FaceMarkers, error) {
+	return ins.DetectFacesContextSafe(context.Background(), img, minSize)
+}
+
+// DetectFacesContext detect face markers from image, returns ctx.Err() once ctx is done
+func (ins *Estimator) DetectFacesContext(ctx context.Context, img image.Image, minSize int) (*core.FaceMarkers, error) {
 	if ins.model == nil {
 		return nil, errors.New("model not inited")
 	}
-	faces, err := ins.model.DetectMultiple(img, minSize)
+	faces, err := core.DetectMultipleContext(ctx, ins.model, img, minSize, ins.detectOpts...)
 	if err != nil {
 		return nil, err
 	}
 	markers := core.NewFaceMarkers(img)
 	for _, face := range faces {
+		if err := face.EmbeddingErr(); err != nil {
+			marker := core.NewFaceMarker(face, "", 0)
+			marker.SetError(err)
+			markers.Append(*marker)
+			continue
+		}
 		person, distance, err := ins.Match(face.Embeddings[0])
 		marker := core.NewFaceMarker(face, person.GetName(), distance)
 		if err != nil {
@@ -237,11 +333,11 @@ func (ins *Estimator) DetectFaces(img image.Image, minSize int) (*core.FaceMarke
 	return markers, nil
 }
 
-// DetectFacesSafe detect face markers from image (multithread safe)
-func (ins *Estimator) DetectFacesSafe(img image.Image, minSize int) (*core.FaceMarkers, error) {
+// DetectFacesContextSafe detect face markers from image, returns ctx.Err() once ctx is done (multithread safe)
+func (ins *Estimator) DetectFacesContextSafe(ctx context.Context, img image.Image, minSize int) (*core.FaceMarkers, error) {
 	ins.lock.RLock()
 	defer ins.lock.RUnlock()
-	return ins.DetectFaces(img, minSize)
+	return ins.DetectFacesContext(ctx, img, minSize)
 }
 
 // Train for trainging classifier
@@ -16,6 +16,7 @@ require (
 	github.com/patrikeh/go-deep v0.0.0-20191210195838-b811ffc4083e
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/tensorflow/tensorflow v1.15.5
+	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5
 	google.golang.org/protobuf v1.27.1
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 	nhooyr.io/websocket v1.8.7
@@ -28,6 +29,5 @@ require (
 	github.com/korandiz/v4l v1.0.4 // indirect
 	github.com/libgoost/encoding-base64 v0.0.0-20190928151742-cd6f75493c10 // indirect
 	gocv.io/x/gocv v0.28.0 // indirect
-	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5 // indirect
 	golang.org/x/sys v0.0.0-20201107080550-4d91cf3a1aaf // indirect
 )
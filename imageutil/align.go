@@ -0,0 +1,110 @@
+package imageutil
+
+import (
+	"image"
+	"math"
+
+	"github.com/disintegration/imaging"
+)
+
+var (
+	// AlignedEyeY represents the canonical eyes row relative to the aligned thumb height
+	AlignedEyeY = 0.425
+	// AlignedEyeDistance represents the canonical distance between eyes relative to the aligned thumb width
+	AlignedEyeDistance = 0.35
+)
+
+// AlignedThumb returns a face crop rotated and scaled so that both eyes are horizontal at canonical positions.
+// leftEye and rightEye are absolute coordinates in img, leftEye being the one on the left side of the image.
+func AlignedThumb(img image.Image, leftEye, rightEye image.Point, size Size) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, size.Width, size.Height))
+	if img.Bounds().Empty() {
+		return dst
+	}
+
+	dx := float64(rightEye.X - leftEye.X)
+	dy := float64(rightEye.Y - leftEye.Y)
+	eyeDist := math.Hypot(dx, dy)
+	if eyeDist < 1 {
+		// Eyes overlap, keep the source orientation and scale.
+		dx, dy, eyeDist = AlignedEyeDistance*float64(size.Width), 0, AlignedEyeDistance*float64(size.Width)
+	}
+
+	// Map each destination pixel back to the source image with the inverse similarity transform.
+	scale := eyeDist / (AlignedEyeDistance * float64(size.Width))
+	cos := dx / eyeDist * scale
+	sin := dy / eyeDist * scale
+	srcMidX := float64(leftEye.X+rightEye.X) / 2
+	srcMidY := float64(leftEye.Y+rightEye.Y) / 2
+	dstMidX := 0.5 * float64(size.Width)
+	dstMidY := AlignedEyeY * float64(size.Height)
+	srcPoint := func(x, y float64) (float64, float64) {
+		px := x + 0.5 - dstMidX
+		py := y + 0.5 - dstMidY
+		return srcMidX + cos*px - sin*py - 0.5, srcMidY + sin*px + cos*py - 0.5
+	}
+
+	// Only the rotated crop region is copied, src may be a native resolution image.
+	region := sampledRegion(img.Bounds(), srcPoint, size)
+	src := imaging.Crop(img, region)
+	for y := 0; y < size.Height; y++ {
+		for x := 0; x < size.Width; x++ {
+			u, v := srcPoint(float64(x), float64(y))
+			i := dst.PixOffset(x, y)
+			bilinear(src, u-float64(region.Min.X), v-float64(region.Min.Y), dst.Pix[i:i+4])
+		}
+	}
+
+	return dst
+}
+
+// sampledRegion returns the pixels of bounds read by bilinear sampling of the destination pixels mapped by srcPoint.
+// The region is never empty, so that samples outside of bounds are clamped to the same edges as in the whole image.
+func sampledRegion(bounds image.Rectangle, srcPoint func(x, y float64) (float64, float64), size Size) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{0, 0}, {float64(size.Width - 1), 0}, {0, float64(size.Height - 1)}, {float64(size.Width - 1), float64(size.Height - 1)}} {
+		u, v := srcPoint(corner[0], corner[1])
+		minX, maxX = math.Min(minX, u), math.Max(maxX, u)
+		minY, maxY = math.Min(minY, v), math.Max(maxY, v)
+	}
+	x0 := clampInt(int(math.Floor(minX)), bounds.Min.X, bounds.Max.X-1)
+	y0 := clampInt(int(math.Floor(minY)), bounds.Min.Y, bounds.Max.Y-1)
+	x1 := clampInt(int(math.Floor(maxX))+2, x0+1, bounds.Max.X)
+	y1 := clampInt(int(math.Floor(maxY))+2, y0+1, bounds.Max.Y)
+	return image.Rect(x0, y0, x1, y1)
+}
+
+// bilinear samples src at (u, v) into out, coordinates outside of src are clamped to the edges
+func bilinear(src *image.NRGBA, u, v float64, out []uint8) {
+	maxX := src.Rect.Dx() - 1
+	maxY := src.Rect.Dy() - 1
+	x0 := int(math.Floor(u))
+	y0 := int(math.Floor(v))
+	fx := u - float64(x0)
+	fy := v - float64(y0)
+	x1 := clampInt(x0+1, 0, maxX)
+	y1 := clampInt(y0+1, 0, maxY)
+	x0 = clampInt(x0, 0, maxX)
+	y0 = clampInt(y0, 0, maxY)
+
+	p00 := src.PixOffset(x0, y0)
+	p10 := src.PixOffset(x1, y0)
+	p01 := src.PixOffset(x0, y1)
+	p11 := src.PixOffset(x1, y1)
+	for c := 0; c < 4; c++ {
+		top := float64(src.Pix[p00+c])*(1-fx) + float64(src.Pix[p10+c])*fx
+		bottom := float64(src.Pix[p01+c])*(1-fx) + float64(src.Pix[p11+c])*fx
+		out[c] = uint8(math.Round(top*(1-fy) + bottom*fy))
+	}
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,30 @@
+package imageutil
+
+import (
+	"bytes"
+	"image"
+	"io"
+
+	// register image decoders
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Load decodes a JPEG, PNG, GIF, BMP, TIFF or WebP image.
+// JPEG, TIFF and WebP images are rotated and flipped upright by their EXIF orientation, other formats are returned as stored.
+func Load(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return Orient(img, ReadOrientation(data)), nil
+}
@@ -0,0 +1,286 @@
+package imageutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+var (
+	topLeft     = color.NRGBA{R: 255, A: 255}
+	topRight    = color.NRGBA{G: 255, A: 255}
+	bottomLeft  = color.NRGBA{B: 255, A: 255}
+	bottomRight = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// uprightImage returns a 32x16 image of 4 colored quadrants
+func uprightImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := topLeft
+			switch {
+			case x >= 16 && y >= 8:
+				c = bottomRight
+			case x >= 16:
+				c = topRight
+			case y >= 8:
+				c = bottomLeft
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// storedImage returns the upright image as stored by a camera with orientation
+func storedImage(orientation Orientation) image.Image {
+	img := uprightImage()
+	switch orientation {
+	case OrientationRotate180:
+		return imaging.Rotate180(img)
+	case OrientationRotate270:
+		return imaging.Rotate90(img)
+	case OrientationRotate90:
+		return imaging.Rotate270(img)
+	}
+	return img
+}
+
+// assertUpright checks the quadrant colors of a loaded image
+func assertUpright(t *testing.T, img image.Image) {
+	if !assert.Equal(t, image.Rect(0, 0, 32, 16), img.Bounds()) {
+		return
+	}
+	for _, p := range []struct {
+		x, y  int
+		color color.NRGBA
+	}{
+		{4, 4, topLeft},
+		{27, 4, topRight},
+		{4, 11, bottomLeft},
+		{27, 11, bottomRight},
+	} {
+		c := color.NRGBAModel.Convert(img.At(p.x, p.y)).(color.NRGBA)
+		assert.InDelta(t, p.color.R, c.R, 8, "red at %d,%d", p.x, p.y)
+		assert.InDelta(t, p.color.G, c.G, 8, "green at %d,%d", p.x, p.y)
+		assert.InDelta(t, p.color.B, c.B, 8, "blue at %d,%d", p.x, p.y)
+	}
+}
+
+// exifTIFF returns a TIFF structure holding an orientation tag
+func exifTIFF(order binary.ByteOrder, orientation Orientation) []byte {
+	buf := new(bytes.Buffer)
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(buf, order, uint16(42))
+	binary.Write(buf, order, uint32(8))
+	binary.Write(buf, order, uint16(1))
+	binary.Write(buf, order, []uint16{exifOrientationTag, 3})
+	binary.Write(buf, order, uint32(1))
+	binary.Write(buf, order, []uint16{uint16(orientation), 0})
+	binary.Write(buf, order, uint32(0))
+	return buf.Bytes()
+}
+
+// jpegFixture encodes img as a JPEG with an EXIF APP1 segment
+func jpegFixture(t *testing.T, img image.Image, orientation Orientation) []byte {
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	exif := append([]byte("Exif\x00\x00"), exifTIFF(binary.BigEndian, orientation)...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(exif)+2))
+	ret := append([]byte{}, data[:2]...)
+	ret = append(ret, app1...)
+	ret = append(ret, exif...)
+	return append(ret, data[2:]...)
+}
+
+// tiffFixture encodes img as a TIFF with an orientation tag added to its first IFD
+func tiffFixture(t *testing.T, img image.Image, orientation Orientation) []byte {
+	buf := new(bytes.Buffer)
+	if err := tiff.Encode(buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	order := binary.ByteOrder(binary.LittleEndian)
+	if string(data[:2]) == "MM" {
+		order = binary.BigEndian
+	}
+	offset := order.Uint32(data[4:8])
+	count := int(order.Uint16(data[offset:]))
+	entries := make([][]byte, 0, count+1)
+	for i := 0; i < count; i++ {
+		start := int(offset) + 2 + i*12
+		entries = append(entries, data[start:start+12])
+	}
+	entry := make([]byte, 12)
+	order.PutUint16(entry[0:], exifOrientationTag)
+	order.PutUint16(entry[2:], 3)
+	order.PutUint32(entry[4:], 1)
+	order.PutUint16(entry[8:], uint16(orientation))
+	entries = append(entries, entry)
+	sort.Slice(entries, func(i, j int) bool {
+		return order.Uint16(entries[i]) < order.Uint16(entries[j])
+	})
+
+	// the new IFD is appended, the former one is left unused
+	ret := append([]byte{}, data...)
+	if len(ret)%2 == 1 {
+		ret = append(ret, 0)
+	}
+	order.PutUint32(ret[4:8], uint32(len(ret)))
+	ifd := make([]byte, 2)
+	order.PutUint16(ifd, uint16(len(entries)))
+	ret = append(ret, ifd...)
+	for _, e := range entries {
+		ret = append(ret, e...)
+	}
+	return append(ret, 0, 0, 0, 0)
+}
+
+// bitWriter writes bits LSB first as VP8L expects
+type bitWriter struct {
+	buf   []byte
+	nbits uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	for i := uint(0); i < n; i++ {
+		if w.nbits%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte((v>>i)&1) << (w.nbits % 8)
+		w.nbits++
+	}
+}
+
+// webpFixture encodes a lossless WebP of one color with an EXIF chunk
+func webpFixture(width int, height int, c color.NRGBA, orientation Orientation) []byte {
+	w := new(bitWriter)
+	w.write(0x2f, 8)
+	w.write(uint32(width-1), 14)
+	w.write(uint32(height-1), 14)
+	w.write(0, 1) // alpha is not used
+	w.write(0, 3) // version
+	w.write(0, 1) // no transform
+	w.write(0, 1) // no color cache
+	w.write(0, 1) // no meta prefix codes
+	// green, red, blue, alpha and distance prefix codes of one 8-bit symbol, read with 0 bits
+	for _, symbol := range []uint8{c.G, c.R, c.B, c.A, 0} {
+		w.write(1, 1) // simple code
+		w.write(0, 1) // one symbol
+		w.write(1, 1) // 8-bit symbol
+		w.write(uint32(symbol), 8)
+	}
+
+	chunk := func(id string, data []byte) []byte {
+		ret := append([]byte(id), 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(ret[4:], uint32(len(data)))
+		ret = append(ret, data...)
+		if len(data)%2 == 1 {
+			ret = append(ret, 0)
+		}
+		return ret
+	}
+	vp8x := make([]byte, 10)
+	vp8x[0] = 0x08 // EXIF flag
+	vp8x[4], vp8x[5], vp8x[6] = byte(width-1), byte((width-1)>>8), byte((width-1)>>16)
+	vp8x[7], vp8x[8], vp8x[9] = byte(height-1), byte((height-1)>>8), byte((height-1)>>16)
+
+	body := []byte("WEBP")
+	body = append(body, chunk("VP8X", vp8x)...)
+	body = append(body, chunk("VP8L", w.buf)...)
+	body = append(body, chunk("EXIF", append([]byte("Exif\x00\x00"), exifTIFF(binary.LittleEndian, orientation)...))...)
+	return chunk("RIFF", body)
+}
+
+func TestLoad(t *testing.T) {
+	orientations := []Orientation{OrientationNormal, OrientationRotate180, OrientationRotate270, OrientationRotate90}
+
+	t.Run("jpeg", func(t *testing.T) {
+		for _, orientation := range orientations {
+			data := jpegFixture(t, storedImage(orientation), orientation)
+			assert.Equal(t, orientation, ReadOrientation(data))
+			img, err := Load(bytes.NewReader(data))
+			if assert.NoError(t, err) {
+				assertUpright(t, img)
+			}
+		}
+	})
+
+	t.Run("tiff", func(t *testing.T) {
+		for _, orientation := range orientations {
+			data := tiffFixture(t, storedImage(orientation), orientation)
+			assert.Equal(t, orientation, ReadOrientation(data))
+			img, err := Load(bytes.NewReader(data))
+			if assert.NoError(t, err) {
+				assertUpright(t, img)
+			}
+		}
+	})
+
+	t.Run("webp", func(t *testing.T) {
+		c := color.NRGBA{R: 10, G: 200, B: 30, A: 255}
+		for _, orientation := range orientations {
+			data := webpFixture(3, 2, c, orientation)
+			assert.Equal(t, orientation, ReadOrientation(data))
+			img, err := Load(bytes.NewReader(data))
+			if !assert.NoError(t, err) {
+				continue
+			}
+			if orientation == OrientationRotate270 || orientation == OrientationRotate90 {
+				assert.Equal(t, image.Rect(0, 0, 2, 3), img.Bounds())
+			} else {
+				assert.Equal(t, image.Rect(0, 0, 3, 2), img.Bounds())
+			}
+			assert.Equal(t, c, color.NRGBAModel.Convert(img.At(1, 1)))
+		}
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		for name, encode := range map[string]func(buf *bytes.Buffer, img image.Image) error{
+			"png": func(buf *bytes.Buffer, img image.Image) error {
+				return png.Encode(buf, img)
+			},
+			"bmp": func(buf *bytes.Buffer, img image.Image) error {
+				return bmp.Encode(buf, img)
+			},
+		} {
+			buf := new(bytes.Buffer)
+			if err := encode(buf, uprightImage()); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, Orientation(0), ReadOrientation(buf.Bytes()), name)
+			img, err := Load(buf)
+			if assert.NoError(t, err, name) {
+				assertUpright(t, img)
+			}
+		}
+	})
+}
+
+func TestOrient(t *testing.T) {
+	img := uprightImage()
+	assertUpright(t, Orient(img, 0))
+	assertUpright(t, Orient(imaging.FlipH(img), OrientationFlipH))
+	assertUpright(t, Orient(imaging.FlipV(img), OrientationFlipV))
+	assertUpright(t, Orient(imaging.Transpose(img), OrientationTranspose))
+	assertUpright(t, Orient(imaging.Transverse(img), OrientationTransverse))
+}
@@ -0,0 +1,142 @@
+package imageutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// Orientation represents an EXIF orientation, 0 means unspecified
+type Orientation int
+
+const (
+	// OrientationNormal the image is upright
+	OrientationNormal Orientation = iota + 1
+	// OrientationFlipH the image is mirrored horizontally
+	OrientationFlipH
+	// OrientationRotate180 the image is upside down
+	OrientationRotate180
+	// OrientationFlipV the image is mirrored vertically
+	OrientationFlipV
+	// OrientationTranspose the image is mirrored along its top-left to bottom-right diagonal
+	OrientationTranspose
+	// OrientationRotate270 the image must be rotated 90° clockwise to be upright
+	OrientationRotate270
+	// OrientationTransverse the image is mirrored along its top-right to bottom-left diagonal
+	OrientationTransverse
+	// OrientationRotate90 the image must be rotated 90° counterclockwise to be upright
+	OrientationRotate90
+)
+
+// exifOrientationTag the orientation tag of TIFF and EXIF IFD0
+const exifOrientationTag = 0x0112
+
+// ReadOrientation returns the EXIF orientation of JPEG, TIFF and WebP image data, 0 if not found
+func ReadOrientation(data []byte) Orientation {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		return jpegOrientation(data)
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return tiffOrientation(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return webpOrientation(data)
+	}
+	return 0
+}
+
+// Orient rotates and flips img upright according to orientation
+func Orient(img image.Image, orientation Orientation) image.Image {
+	switch orientation {
+	case OrientationFlipH:
+		return imaging.FlipH(img)
+	case OrientationRotate180:
+		return imaging.Rotate180(img)
+	case OrientationFlipV:
+		return imaging.FlipV(img)
+	case OrientationTranspose:
+		return imaging.Transpose(img)
+	case OrientationRotate270:
+		return imaging.Rotate270(img)
+	case OrientationTransverse:
+		return imaging.Transverse(img)
+	case OrientationRotate90:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// jpegOrientation reads the orientation of the EXIF APP1 segment of a JPEG image
+func jpegOrientation(data []byte) Orientation {
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return 0
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		// start of scan, image data follows
+		if marker == 0xda || size < 2 || i+2+size > len(data) {
+			return 0
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 0
+}
+
+// webpOrientation reads the orientation of the EXIF chunk of a WebP image
+func webpOrientation(data []byte) Orientation {
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		if size < 0 || i+8+size > len(data) {
+			return 0
+		}
+		if string(data[i:i+4]) == "EXIF" {
+			// the chunk may start with the EXIF header of JPEG APP1 segments
+			return tiffOrientation(bytes.TrimPrefix(data[i+8:i+8+size], []byte("Exif\x00\x00")))
+		}
+		// chunks are padded to an even size
+		i += 8 + size + size&1
+	}
+	return 0
+}
+
+// tiffOrientation reads the orientation tag of the first IFD of TIFF structured data
+func tiffOrientation(data []byte) Orientation {
+	if len(data) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	offset := int(order.Uint32(data[4:8]))
+	if offset < 8 || offset+2 > len(data) {
+		return 0
+	}
+	count := int(order.Uint16(data[offset : offset+2]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(data) {
+			return 0
+		}
+		if order.Uint16(data[entry:entry+2]) != exifOrientationTag {
+			continue
+		}
+		// a SHORT value is stored in the first bytes of the value field
+		if v := Orientation(order.Uint16(data[entry+8 : entry+10])); v >= OrientationNormal && v <= OrientationRotate90 {
+			return v
+		}
+		return 0
+	}
+	return 0
+}
@@ -17,13 +17,39 @@ func (fn optionFunc) apply(ins *Estimator) error {
 }
 
 // WithModel set net model with model path
-func WithModel(modelPath string) Option {
+func WithModel(modelPath string, opts ...core.NetOption) Option {
 	return optionFunc(func(ins *Estimator) error {
-		ins.model = core.NewNet(modelPath)
+		ins.model = core.NewNet(modelPath, opts...)
 		return nil
 	})
 }
 
+// WithEmbedder set face embedding backend
+func WithEmbedder(embedder core.Embedder) Option {
+	return optionFunc(func(ins *Estimator) error {
+		ins.model = embedder
+		return nil
+	})
+}
+
+// WithDetectOptions set face detection pipeline options
+func WithDetectOptions(opts ...core.DetectOption) Option {
+	return optionFunc(func(ins *Estimator) error {
+		ins.detectOpts = append(ins.detectOpts, opts...)
+		return nil
+	})
+}
+
+// WithDetector set face detection backend, pigo is used by default
+func WithDetector(detector core.Detector) Option {
+	return WithDetectOptions(core.WithDetector(detector))
+}
+
+// WithMTCNN set MTCNN face detection backend with the frozen graph path made by scripts/converter.py
+func WithMTCNN(modelPath string, opts ...core.MTCNNOption) Option {
+	return WithDetector(core.NewMTCNN(modelPath, opts...))
+}
+
 // WithDB set db with dbpath
 func WithDB(dbPath string) Option {
 	return optionFunc(func(ins *Estimator) error {
@@ -34,6 +60,17 @@ func WithDB(dbPath string) Option {
 	})
 }
 
+// WithMetric set people distance metric, should be applied after WithDB
+func WithMetric(metric core.Metric) Option {
+	return optionFunc(func(ins *Estimator) error {
+		if ins.db == nil {
+			ins.db = NewStorage(nil, nil)
+		}
+		ins.db.SetMetric(metric)
+		return nil
+	})
+}
+
 // WithFontPath set font with font path
 func WithFontPath(fontPath string) Option {
 	return optionFunc(func(ins *Estimator) error {
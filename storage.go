@@ -2,11 +2,8 @@ package facenet
 
 import (
 	"archive/zip"
-	"io"
 	"os"
 
-	"google.golang.org/protobuf/proto"
-
 	"github.com/bububa/facenet/classifier"
 	"github.com/bububa/facenet/core"
 )
@@ -21,21 +18,31 @@ const (
 // Storage represents db storage
 type Storage struct {
 	people     *core.People
+	index      *core.PeopleIndex
 	classifier classifier.Classifier
 }
 
 // NewStorage returns new Storage
 func NewStorage(people *core.People, classifier classifier.Classifier) *Storage {
-	return &Storage{
-		people:     people,
+	s := &Storage{
 		classifier: classifier,
 	}
+	if people != nil {
+		s.setPeople(people)
+	}
+	return s
+}
+
+// setPeople sets people and its vector index
+func (s *Storage) setPeople(people *core.People) {
+	s.people = people
+	s.index = core.NewPeopleIndex(people)
 }
 
 // Load load storage from file
 func (s *Storage) Load(fname string) error {
 	if s.people == nil {
-		s.people = new(core.People)
+		s.setPeople(new(core.People))
 	}
 	zipFn, err := zip.OpenReader(fname)
 	if err != nil {
@@ -57,14 +64,12 @@ func (s *Storage) Load(fname string) error {
 			if err != nil {
 				return err
 			}
-			buf, err := io.ReadAll(r)
+			err = core.LoadPeople(r, s.people)
+			// people are changed in place, even if invalid
+			s.index.Rebuild()
 			if err != nil {
 				return err
 			}
-			if err = proto.Unmarshal(buf, s.people); err != nil {
-				return err
-			}
-			s.people.Setup()
 		case ClassifierFilename:
 			r, err := f.Open()
 			if err != nil {
@@ -111,17 +116,32 @@ func (s *Storage) SetClassifier(c classifier.Classifier) {
 	s.classifier = c
 }
 
-// People returns people
+// SetMetric set people distance metric
+func (s *Storage) SetMetric(metric core.Metric) {
+	if s.people == nil {
+		s.setPeople(new(core.People))
+	}
+	s.index.SetMetric(metric)
+}
+
+// People returns people, changes other than by Add, Delete and SetMetric must be followed by Reindex
 func (s *Storage) People() *core.People {
 	return s.people
 }
 
+// Reindex indexes people again on the next match, after people were changed directly
+func (s *Storage) Reindex() {
+	if s.index != nil {
+		s.index.Rebuild()
+	}
+}
+
 // Add add person to people
 func (s *Storage) Add(items ...*core.Person) {
 	if s.people == nil {
-		s.people = new(core.People)
+		s.setPeople(new(core.People))
 	}
-	s.people.Append(items...)
+	s.index.Append(items...)
 }
 
 // Delete delete a person by name
@@ -129,7 +149,7 @@ func (s *Storage) Delete(name string) bool {
 	if s.people == nil {
 		return false
 	}
-	return s.people.Delete(name)
+	return s.index.Delete(name)
 }
 
 // Predict returns predictation results
@@ -149,7 +169,10 @@ func (s *Storage) Predict(input []float32) ([]*core.Person, []float64, error) {
 // Match returns best match result
 func (s *Storage) Match(input []float32) (*core.Person, float64, error) {
 	if s.classifier == nil {
-		return s.people.Match(input)
+		if s.index == nil {
+			return s.people.Match(input)
+		}
+		return s.index.Match(input)
 	}
 	idx, score := s.classifier.Match(input)
 	if idx < 0 {
@@ -159,6 +182,34 @@ func (s *Storage) Match(input []float32) (*core.Person, float64, error) {
 	return people[idx], score, nil
 }
 
+// MatchTopK returns the k persons nearest to input sorted by distance,
+// with classifier scores if a classifier is set and trained with the current people
+func (s *Storage) MatchTopK(input []float32, k int) ([]core.Candidate, error) {
+	if s.people == nil {
+		return nil, core.NewError(core.NothingMatchErr, "no match results")
+	}
+	candidates, err := s.index.MatchTopK(input, k)
+	if err != nil || s.classifier == nil {
+		return candidates, err
+	}
+	// scores are aligned to people list, an untrained classifier or one trained before people changed has no usable scores
+	scores := s.classifier.Predict(input)
+	list := s.people.GetList()
+	if len(scores) != len(list) {
+		return candidates, nil
+	}
+	indexes := make(map[*core.Person]int, len(list))
+	for idx, person := range list {
+		indexes[person] = idx
+	}
+	for i, candidate := range candidates {
+		if idx, found := indexes[candidate.Person]; found {
+			candidates[i].Score = scores[idx]
+		}
+	}
+	return candidates, nil
+}
+
 // Train for trainging classifier
 func (s *Storage) Train(split float64, iterations int, verbosity int) {
 	s.classifier.Train(s.people, split, iterations, verbosity)